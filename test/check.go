@@ -0,0 +1,163 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// The IDs of the problems that are not found by lint rules
+const (
+	RuleDecode        = "decode"
+	RuleValidation    = "validation"
+	RuleDeprecatedAPI = "deprecated-api"
+	RuleReference     = "reference"
+	RuleKinds         = "kinds"
+)
+
+// Options configures Check.
+type Options struct {
+	// Target is the release to check deprecated APIs against, e.g. "v1.10".
+	// Deprecated APIs are not checked if it is empty.
+	Target string
+	// Rules are the lint rules to run.
+	Rules []*Rule
+}
+
+// Problem is a problem of an example at a position of its file.
+type Problem struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	// Column is 0 if the problem is not at a field of the document.
+	Column   int      `json:"column,omitempty"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	pos := fmt.Sprintf("%s:%d", p.Path, p.Line)
+	if p.Column > 0 {
+		pos += fmt.Sprintf(":%d", p.Column)
+	}
+	return fmt.Sprintf("%s: %s [%s] %s", pos, p.Severity, p.Rule, p.Message)
+}
+
+// position returns the position of a field of doc, or of doc itself if path
+// is empty.
+func (doc Document) position(path string) (int, int) {
+	if doc.Node == nil {
+		return doc.Line, 0
+	}
+	n := doc.Node.Find(path)
+	return n.Line, n.Column
+}
+
+// Check decodes, validates and lints the documents of files, and resolves the
+// references between the objects of the files of each directory. Call
+// SetUpValidation first.
+func Check(files []*File, opts Options) []Problem {
+	var problems []Problem
+	docs := map[string][]Document{}
+	sets := map[string][]Entry{}
+	var dirs []string
+	for _, f := range files {
+		docs[f.Path] = f.Documents
+		expected, err := ReadExpectedKinds(f.Path)
+		if err != nil {
+			problems = append(problems, Problem{Path: f.Path, Line: 1, Rule: RuleKinds, Severity: SeverityError, Message: err.Error()})
+			expected = nil
+		} else if expected != nil && len(expected) != len(f.Documents) {
+			msg := fmt.Sprintf("its %s file lists %d kinds, but it has %d documents", ExpectedKindsExt, len(expected), len(f.Documents))
+			problems = append(problems, Problem{Path: f.Path, Line: 1, Rule: RuleKinds, Severity: SeverityError, Message: msg})
+			expected = nil
+		}
+		for i, doc := range f.Documents {
+			var want *schema.GroupVersionKind
+			if expected != nil {
+				want = &expected[i]
+			}
+			problems = append(problems, checkDocument(f.Path, doc, opts.Target, want)...)
+		}
+		for _, finding := range Lint(f.JSON(), opts.Rules) {
+			line, column := f.Documents[finding.Doc].position("")
+			problems = append(problems, Problem{Path: f.Path, Line: line, Column: column, Rule: finding.Rule.ID, Severity: finding.Rule.Severity, Message: finding.Message})
+		}
+		dir := filepath.Dir(f.Path)
+		if _, ok := sets[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		sets[dir] = append(sets[dir], f.Entries()...)
+	}
+
+	for _, dir := range dirs {
+		for _, p := range CheckReferences(sets[dir]) {
+			severity := SeverityWarning
+			if p.LikelyMistake {
+				severity = SeverityError
+			}
+			line, column := docs[p.From.Path][p.From.Doc].position("")
+			problems = append(problems, Problem{Path: p.From.Path, Line: line, Column: column, Rule: RuleReference, Severity: severity, Message: p.Message})
+		}
+	}
+	return problems
+}
+
+// checkDocument decodes and validates a document, and checks it for a
+// deprecated API as of the target release. If want is set, the document must
+// be of that kind, as listed by the sidecar file of the example.
+func checkDocument(path string, doc Document, target string, want *schema.GroupVersionKind) []Problem {
+	var problems []Problem
+	add := func(field, rule string, severity Severity, msg string) {
+		line, column := doc.position(field)
+		problems = append(problems, Problem{Path: path, Line: line, Column: column, Rule: rule, Severity: severity, Message: msg})
+	}
+
+	var obj Object
+	if err := json.Unmarshal(doc.JSON, &obj); err == nil && target != "" {
+		apiVersion, kind := obj.GetString("apiVersion"), obj.GetString("kind")
+		if d := FindDeprecation(apiVersion, kind, target); d != nil {
+			severity := SeverityWarning
+			if d.Removed(target) {
+				severity = SeverityError
+			}
+			add("apiVersion", RuleDeprecatedAPI, severity, fmt.Sprintf("%s %s is %v", apiVersion, kind, d))
+		}
+	}
+
+	decoded, gvk, err := Decode(doc.JSON)
+	if err != nil {
+		add("", RuleDecode, SeverityError, fmt.Sprintf("did not decode correctly: %v", err))
+		return problems
+	}
+	if want != nil && *gvk != *want {
+		add("kind", RuleKinds, SeverityError, fmt.Sprintf("is a %q, but its %s file expects a %q", FormatKind(*gvk), ExpectedKindsExt, FormatKind(*want)))
+		return problems
+	}
+	errors := ValidateObject(decoded)
+	if HasNoValidation(errors) {
+		return problems
+	}
+	for _, err := range errors {
+		add(err.Field, RuleValidation, SeverityError, err.Error())
+	}
+	return problems
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// example-lint runs the checks of the example tests on some example files,
+// so that they can be checked before pushing them.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	examples "k8s.io/website/test"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs example-lint with the command line args, and returns its exit
+// code: 1 if an example has an error, 2 for a usage error.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("example-lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "the output format: text, json or sarif")
+	fix := flags.Bool("fix", false, "rewrite the apiVersion of the examples that use a deprecated API before checking them")
+	target := flags.String("target", "", "the release to check for deprecated APIs, e.g. v1.16 (default the release of the docs, from test/schemas/versions)")
+	rules := flags.String("rules", "", "comma-separated IDs of the lint rules to run (default all)")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: example-lint [--format=text|json|sarif] [--fix] [--target=<release>] [--rules=<ids>] <file or directory>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" && *format != "sarif" {
+		fmt.Fprintf(stderr, "Unknown format %q, use text, json or sarif.\n", *format)
+		return 2
+	}
+	fail := func(err error) int {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	enabled, err := examples.EnabledRules(*rules)
+	if err != nil {
+		return fail(err)
+	}
+
+	// The ignore file and the release of the docs are found in the website repo
+	var ignore *examples.Ignore
+	release := *target
+	if root := siteRoot(flags.Arg(0)); root != "" {
+		if ignore, err = examples.ReadIgnore(filepath.Join(root, "test", examples.IgnoreFile), root); err != nil {
+			return fail(err)
+		}
+		if release == "" {
+			versions, err := examples.ReadSchemaVersions(filepath.Join(root, "test", "schemas", "versions"))
+			if err != nil {
+				return fail(err)
+			}
+			release = versions[0]
+		}
+	}
+	if *fix && release == "" {
+		fmt.Fprintf(stderr, "Please specify the release to fix deprecated APIs for with --target.\n")
+		return 2
+	}
+
+	files, err := readFiles(flags.Args(), ignore)
+	if err != nil {
+		return fail(err)
+	}
+	if *fix {
+		for i, f := range files {
+			fixed, skipped, err := examples.FixFile(f.Path, release)
+			if err != nil {
+				return fail(err)
+			}
+			for _, msg := range skipped {
+				fmt.Fprintf(stderr, "%s: not rewritten, %s\n", f.Path, msg)
+			}
+			if !fixed {
+				continue
+			}
+			fmt.Fprintf(stderr, "%s: rewrote the deprecated apiVersions for %s, please review it\n", f.Path, release)
+			if files[i], err = examples.ReadFile(f.Path); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	examples.SetUpValidation()
+	problems := examples.Check(files, examples.Options{Target: release, Rules: enabled})
+	switch *format {
+	case "json":
+		err = writeJSON(stdout, problems)
+	case "sarif":
+		err = writeSARIF(stdout, problems, enabled)
+	default:
+		for _, p := range problems {
+			fmt.Fprintln(stdout, p)
+		}
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	for _, p := range problems {
+		if p.Severity == examples.SeverityError {
+			return 1
+		}
+	}
+	return 0
+}
+
+// siteRoot returns the root of the website repo that path is in, or "".
+func siteRoot(path string) string {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "test", examples.IgnoreFile)); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readFiles reads the examples at paths. The examples below a directory are
+// skipped if ignore matches them, the files named explicitly are not.
+func readFiles(paths []string, ignore *examples.Ignore) ([]*examples.File, error) {
+	var files []*examples.File
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			err := examples.Walk(path, ignore, func(f *examples.File) {
+				files = append(files, f)
+			})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !examples.IsExample(path) {
+			return nil, fmt.Errorf("%s: only .yaml and .json files can be checked", path)
+		}
+		f, err := examples.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+func writeJSON(w io.Writer, problems []examples.Problem) error {
+	if problems == nil {
+		problems = []examples.Problem{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(problems)
+}
+
+// The parts of the SARIF 2.1.0 format that example-lint writes
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func writeSARIF(w io.Writer, problems []examples.Problem, enabled []*examples.Rule) error {
+	driver := sarifDriver{
+		Name:           "example-lint",
+		InformationURI: "https://github.com/kubernetes/website/tree/master/test",
+		Rules: []sarifRule{
+			{ID: examples.RuleDecode, ShortDescription: sarifMessage{"examples must decode into a built-in kind"}},
+			{ID: examples.RuleValidation, ShortDescription: sarifMessage{"examples must be valid Kubernetes objects"}},
+			{ID: examples.RuleDeprecatedAPI, ShortDescription: sarifMessage{"examples should not use deprecated APIs"}},
+			{ID: examples.RuleReference, ShortDescription: sarifMessage{"references between the objects of examples should resolve"}},
+			{ID: examples.RuleKinds, ShortDescription: sarifMessage{"examples must be of the kinds that their .kinds files list"}},
+		},
+	}
+	for _, rule := range enabled {
+		driver.Rules = append(driver.Rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{rule.Description}})
+	}
+	results := []sarifResult{}
+	for _, p := range problems {
+		results = append(results, sarifResult{
+			RuleID:  p.Rule,
+			Level:   string(p.Severity),
+			Message: sarifMessage{p.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(p.Path)},
+				Region:           sarifRegion{StartLine: p.Line, StartColumn: p.Column},
+			}}},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	examples "k8s.io/website/test"
+)
+
+// A valid Deployment of a deprecated API, which breaks the latest-image and
+// no-resources rules
+const deployment = `apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - name: nginx
+        image: nginx
+        livenessProbe:
+          httpGet:
+            path: /
+            port: 80
+        readinessProbe:
+          httpGet:
+            path: /
+            port: 80
+`
+
+// writeSite writes a website repo with an ignore file and the files, and
+// returns its root.
+func writeSite(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "example-lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["test/"+examples.IgnoreFile] = "docs/ignored  # not an example\n"
+	files["test/schemas/versions"] = "v1.10 v1.10.0\n"
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// runLint runs example-lint with args, and returns its exit code and output.
+func runLint(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunJSON(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"docs/deployment.yaml":     deployment,
+		"docs/ignored/broken.yaml": "---\nkind: Pod\n",
+	})
+	defer os.RemoveAll(root)
+
+	code, stdout, stderr := runLint("--format=json", filepath.Join(root, "docs"))
+	if code != 0 {
+		t.Fatalf("exit code %d, want 0: %s", code, stderr)
+	}
+	var problems []examples.Problem
+	if err := json.Unmarshal([]byte(stdout), &problems); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout)
+	}
+	var got []string
+	for _, p := range problems {
+		if filepath.Base(p.Path) != "deployment.yaml" {
+			t.Errorf("unexpected problem of an ignored file: %v", p)
+		}
+		got = append(got, string(p.Severity)+" "+p.Rule)
+	}
+	// the release of the docs comes from test/schemas/versions
+	want := "warning deprecated-api, warning latest-image, warning no-resources"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("unexpected problems %q, want %q", strings.Join(got, ", "), want)
+	}
+	if len(problems) > 0 && (problems[0].Line != 1 || problems[0].Column != 1) {
+		t.Errorf("deprecated-api at %d:%d, want 1:1", problems[0].Line, problems[0].Column)
+	}
+
+	// the API is removed in v1.16, which is an error
+	code, stdout, _ = runLint("--target=v1.16", filepath.Join(root, "docs", "deployment.yaml"))
+	if code != 1 || !strings.Contains(stdout, "deployment.yaml:1:1: error [deprecated-api]") {
+		t.Errorf("exit code %d for a removed API, want 1:\n%s", code, stdout)
+	}
+
+	// an ignored file is checked when it is named explicitly
+	code, _, stderr = runLint(filepath.Join(root, "docs", "ignored", "broken.yaml"))
+	if code != 1 || !strings.Contains(stderr, "cannot start with") {
+		t.Errorf("exit code %d for an invalid example, want 1: %s", code, stderr)
+	}
+}
+
+func TestRunRules(t *testing.T) {
+	root := writeSite(t, map[string]string{"docs/deployment.yaml": deployment})
+	defer os.RemoveAll(root)
+
+	code, stdout, stderr := runLint("--rules=latest-image", "--target=v1.8", filepath.Join(root, "docs", "deployment.yaml"))
+	if code != 0 {
+		t.Fatalf("exit code %d, want 0: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "warning [latest-image]") {
+		t.Errorf("expected only a latest-image warning, got:\n%s", stdout)
+	}
+
+	if code, _, stderr := runLint("--rules=no-such-rule", root); code != 1 || stderr == "" {
+		t.Errorf("exit code %d for an unknown rule, want 1 and an error", code)
+	}
+	if code, _, _ := runLint("--format=xml", root); code != 2 {
+		t.Errorf("exit code %d for an unknown format, want 2", code)
+	}
+	if code, _, _ := runLint(); code != 2 {
+		t.Errorf("exit code %d without paths, want 2", code)
+	}
+	if code, _, stderr := runLint(filepath.Join(root, "test", "schemas", "versions")); code != 1 || !strings.Contains(stderr, "only .yaml and .json files") {
+		t.Errorf("exit code %d for a file that is not an example, want 1: %s", code, stderr)
+	}
+}
+
+func TestRunKinds(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"docs/deployment.yaml":  deployment,
+		"docs/deployment.kinds": "# the only document\napps/v1 StatefulSet\n",
+	})
+	defer os.RemoveAll(root)
+
+	code, stdout, _ := runLint("--rules=latest-image", "--target=v1.8", filepath.Join(root, "docs"))
+	if code != 1 || !strings.Contains(stdout, "error [kinds]") || !strings.Contains(stdout, ".kinds file expects a \"apps/v1 StatefulSet\"") {
+		t.Errorf("exit code %d for an example of another kind, want 1:\n%s", code, stdout)
+	}
+
+	ioutil.WriteFile(filepath.Join(root, "docs", "deployment.kinds"), []byte("v1 Service\napps/v1 Deployment\n"), 0644)
+	code, stdout, _ = runLint("--rules=latest-image", "--target=v1.8", filepath.Join(root, "docs"))
+	if code != 1 || !strings.Contains(stdout, "deployment.yaml:1: error [kinds] its .kinds file lists 2 kinds, but it has 1 documents") {
+		t.Errorf("exit code %d for a wrong number of kinds, want 1:\n%s", code, stdout)
+	}
+}
+
+func TestRunFix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "example-lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "deployment.yaml")
+	if err := ioutil.WriteFile(path, []byte(deployment), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// outside of the website repo, there is no default release
+	if code, _, stderr := runLint("--fix", dir); code != 2 || !strings.Contains(stderr, "--target") {
+		t.Errorf("exit code %d for --fix without a release, want 2: %s", code, stderr)
+	}
+
+	code, stdout, stderr := runLint("--fix", "--target=v1.10", "--format=json", dir)
+	if code != 0 {
+		t.Fatalf("exit code %d, want 0: %s", code, stderr)
+	}
+	if !strings.Contains(stderr, "rewrote the deprecated apiVersions for v1.10") {
+		t.Errorf("expected a note about the rewrite, got %q", stderr)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(deployment, "extensions/v1beta1", "apps/v1", 1); string(data) != want {
+		t.Errorf("unexpected rewrite:\n%s\nwant:\n%s", data, want)
+	}
+	// the rewritten file is checked
+	if strings.Contains(stdout, "deprecated-api") {
+		t.Errorf("expected no deprecated API after --fix, got:\n%s", stdout)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := writeJSON(&out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(out.String()); got != "[]" {
+		t.Errorf("no problems are written as %q, want []", got)
+	}
+
+	out.Reset()
+	problems := []examples.Problem{{Path: "docs/pod.yaml", Line: 3, Rule: examples.RuleDecode, Severity: examples.SeverityError, Message: "bad"}}
+	if err := writeJSON(&out, problems); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"path": "docs/pod.yaml", "line": 3.0, "rule": "decode", "severity": "error", "message": "bad"}
+	if len(got) != 1 || len(got[0]) != len(want) {
+		t.Fatalf("unexpected problems %v, want [%v]", got, want)
+	}
+	for k, v := range want {
+		if got[0][k] != v {
+			t.Errorf("%s is %v, want %v", k, got[0][k], v)
+		}
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	enabled, err := examples.EnabledRules("latest-image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems := []examples.Problem{
+		{Path: filepath.Join("docs", "pod.yaml"), Line: 7, Column: 9, Rule: "latest-image", Severity: examples.SeverityWarning, Message: "use a tag"},
+		{Path: "docs/svc.yaml", Line: 1, Rule: examples.RuleValidation, Severity: examples.SeverityError, Message: "invalid"},
+	}
+	var out bytes.Buffer
+	if err := writeSARIF(&out, problems, enabled); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || !strings.Contains(log.Schema, "sarif-2.1.0") || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF log %+v", log)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "example-lint" {
+		t.Errorf("unexpected tool %q", run.Tool.Driver.Name)
+	}
+	var ids []string
+	for _, rule := range run.Tool.Driver.Rules {
+		ids = append(ids, rule.ID)
+		if rule.ShortDescription.Text == "" {
+			t.Errorf("rule %s has no description", rule.ID)
+		}
+	}
+	if got, want := strings.Join(ids, " "), "decode validation deprecated-api reference kinds latest-image"; got != want {
+		t.Errorf("unexpected rules %q, want %q", got, want)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("unexpected results %+v", run.Results)
+	}
+	r := run.Results[0]
+	location := r.Locations[0].PhysicalLocation
+	if r.RuleID != "latest-image" || r.Level != "warning" || r.Message.Text != "use a tag" ||
+		location.ArtifactLocation.URI != "docs/pod.yaml" || location.Region.StartLine != 7 || location.Region.StartColumn != 9 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if r := run.Results[1]; r.Level != "error" || r.Locations[0].PhysicalLocation.Region.StartColumn != 0 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	// SARIF requires a results array, even an empty one
+	out.Reset()
+	if err := writeSARIF(&out, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"results": []`) {
+		t.Errorf("expected an empty results array:\n%s", out.String())
+	}
+}
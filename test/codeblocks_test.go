@@ -0,0 +1,268 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	examples "k8s.io/website/test"
+)
+
+// noValidate opts a code block out of validation in an HTML comment on the
+// line before it, e.g. "<!-- no-validate -->". Kramdown does not support
+// attributes on fences, so it cannot be one.
+const noValidate = "no-validate"
+
+// To catch the opening fence of a code block, with its indentation, fence and
+// language
+var fenceRegexp = regexp.MustCompile("^([ \\t]*)(```+|~~~+)[ \\t]*([^ \\t`]*)")
+
+// To catch the opt-out comment before a code block
+var noValidateCommentRegexp = regexp.MustCompile(`^\s*<!--\s*` + noValidate + `\s*-->\s*$`)
+
+// To catch the top-level apiVersion and kind of a complete object, in YAML or
+// JSON
+var apiVersionRegexp = regexp.MustCompile(`(?m)(?:^|[{,])\s*"?apiVersion"?\s*:`)
+var kindRegexp = regexp.MustCompile(`(?m)(?:^|[{,])\s*"?kind"?\s*:`)
+
+// An elided part of an object, e.g. "..." or "# ..."
+var elisionRegexp = regexp.MustCompile(`(?m)^\s*(?:#\s*)?\.{3}`)
+
+// codeBlock is a fenced YAML or JSON code block of a Markdown page.
+type codeBlock struct {
+	// line is the line number of the first line of the content.
+	line int
+	// indent is the indentation of the fence, which is removed from the
+	// content.
+	indent  int
+	lang    string
+	content string
+	// noValidate is set if the block is opted out of validation.
+	noValidate bool
+}
+
+// markdownCodeBlocks returns the YAML and JSON code blocks of a Markdown
+// page, including blocks without a language that hold a JSON object. The
+// indentation of the fence, e.g. in a list item, is removed from the content.
+func markdownCodeBlocks(data string) []codeBlock {
+	var blocks []codeBlock
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		m := fenceRegexp.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		indent, fence, lang := m[1], m[2], strings.ToLower(m[3])
+		skip := false
+		for j := i - 1; j >= 0; j-- {
+			if strings.TrimSpace(lines[j]) == "" {
+				continue
+			}
+			skip = noValidateCommentRegexp.MatchString(lines[j])
+			break
+		}
+
+		start := i + 1
+		for i = start; i < len(lines); i++ {
+			if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+				break
+			}
+		}
+		var content []string
+		for _, line := range lines[start:i] {
+			content = append(content, strings.TrimPrefix(line, indent))
+		}
+		block := codeBlock{line: start + 1, indent: len(indent), lang: lang, content: strings.Join(content, "\n"), noValidate: skip}
+		switch {
+		case lang == "yaml" || lang == "yml" || lang == "json":
+		case lang == "" && strings.HasPrefix(strings.TrimSpace(block.content), "{"):
+			block.lang = "json"
+		default:
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// blockDocument is a YAML document of a code block.
+type blockDocument struct {
+	// line is the line number of the document in the Markdown page.
+	line    int
+	indent  int
+	content string
+}
+
+// documents splits the content of b into YAML documents. A JSON block is a
+// single document.
+func (b codeBlock) documents() []blockDocument {
+	if b.lang == "json" {
+		return []blockDocument{{line: b.line, indent: b.indent, content: b.content}}
+	}
+	var docs []blockDocument
+	doc := blockDocument{line: b.line, indent: b.indent}
+	var lines []string
+	for i, line := range strings.Split(b.content, "\n") {
+		if strings.TrimRight(line, " \t") == "---" {
+			doc.content = strings.Join(lines, "\n")
+			docs = append(docs, doc)
+			doc, lines = blockDocument{line: b.line + i + 1, indent: b.indent}, nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	doc.content = strings.Join(lines, "\n")
+	return append(docs, doc)
+}
+
+// isFragment reports whether a document only shows a part of an object, or
+// is rendered by Liquid, and cannot be validated on its own.
+func isFragment(content string) bool {
+	return !apiVersionRegexp.MatchString(content) ||
+		!kindRegexp.MatchString(content) ||
+		elisionRegexp.MatchString(content) ||
+		strings.Contains(content, "{{") ||
+		strings.Contains(content, "{%")
+}
+
+// Validates the complete Kubernetes objects in the YAML and JSON code blocks
+// of every Markdown page below exampleRoots. Blocks opt out with noValidate.
+func TestMarkdownCodeBlocks(t *testing.T) {
+	examples.SetUpValidation()
+	for _, root := range exampleRoots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || filepath.Ext(path) != ".md" {
+				return nil
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, block := range markdownCodeBlocks(string(data)) {
+				if block.noValidate {
+					continue
+				}
+				for _, doc := range block.documents() {
+					if isFragment(doc.content) {
+						continue
+					}
+					json, err := yaml.ToJSON([]byte(doc.content))
+					if err != nil {
+						t.Errorf("%s:%d: could not be converted to JSON: %v", path, doc.line, err)
+						continue
+					}
+					obj, gvk, err := examples.Decode(json)
+					if runtime.IsNotRegisteredError(err) && gvk != nil {
+						t.Logf("%s:%d: skipping %s, which is not a built-in kind", path, doc.line, examples.FormatKind(*gvk))
+						continue
+					}
+					if err != nil {
+						t.Errorf("%s:%d: did not decode correctly: %v", path, doc.line, err)
+						continue
+					}
+					if errors := examples.ValidateObject(obj); len(errors) > 0 && !examples.HasNoValidation(errors) {
+						node := examples.ParseNode(doc.content, doc.line, doc.indent)
+						t.Errorf("%s:%d: did not validate correctly:\n%s", path, doc.line, strings.Join(examples.FormatFieldErrors(path, node, doc.line, errors), "\n"))
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unable to walk %s: %v", root, err)
+		}
+	}
+}
+
+func TestMarkdownCodeBlockParsing(t *testing.T) {
+	page := strings.Join([]string{
+		"# Page",
+		"",
+		"```yaml",
+		"apiVersion: v1",
+		"kind: Service",
+		"---",
+		"apiVersion: v1",
+		"kind: Pod",
+		"```",
+		"",
+		"1. In a list:",
+		"",
+		"    ```json",
+		"    {\"apiVersion\": \"v1\", \"kind\": \"Pod\"}",
+		"    ```",
+		"",
+		"```shell",
+		"kubectl get pods",
+		"```",
+		"",
+		"<!--no-validate-->",
+		"```yaml",
+		"kind: Pod",
+		"```",
+		"<!-- no-validate -->",
+		"",
+		"```",
+		"{}",
+		"```",
+	}, "\n")
+
+	blocks := markdownCodeBlocks(page)
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got %+v", blocks)
+	}
+	var docs []string
+	for _, doc := range blocks[0].documents() {
+		docs = append(docs, fmt.Sprintf("%d:%s", doc.line, doc.content))
+	}
+	if got, want := strings.Join(docs, "|"), "4:apiVersion: v1\nkind: Service|7:apiVersion: v1\nkind: Pod"; got != want {
+		t.Errorf("unexpected documents %q, want %q", got, want)
+	}
+	if b := blocks[1]; b.line != 14 || b.indent != 4 || b.lang != "json" || b.content != `{"apiVersion": "v1", "kind": "Pod"}` || b.noValidate {
+		t.Errorf("unexpected indented block %+v", b)
+	}
+	if !blocks[2].noValidate || !blocks[3].noValidate {
+		t.Errorf("expected the last blocks to opt out of validation, got %+v", blocks[2:])
+	}
+	if blocks[3].lang != "json" {
+		t.Errorf("expected a block holding an object to be JSON, got %q", blocks[3].lang)
+	}
+
+	for content, want := range map[string]bool{
+		"apiVersion: v1\nkind: Pod":                 false,
+		"kind: Pod":                                 true,
+		"apiVersion: v1\nkind: Pod\nspec:\n  ...":   true,
+		"apiVersion: v1\nkind: Pod\n# ...":          true,
+		"apiVersion: v1\nkind: {{ page.kind }}":     true,
+		`{"apiVersion": "v1", "kind": "Namespace"}`: false,
+	} {
+		if got := isFragment(content); got != want {
+			t.Errorf("isFragment(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// DeprecatedAPI is an apiVersion that is deprecated for some or all of its
+// kinds.
+type DeprecatedAPI struct {
+	APIVersion string
+	// Kinds are the deprecated kinds, or empty for all kinds.
+	Kinds []string
+	// DeprecatedIn and RemovedIn are releases, e.g. "v1.9". RemovedIn is
+	// empty if no removal is planned yet.
+	DeprecatedIn string
+	RemovedIn    string
+	Replacement  string
+	// RequiresSelector is set if the replacement requires spec.selector,
+	// which the deprecated API defaulted from the labels of the pod
+	// template. FixDeprecatedAPIs does not rewrite documents without one.
+	RequiresSelector bool
+	// KindChanges is set if the replacement has another kind, or needs
+	// changes beyond the apiVersion. FixDeprecatedAPIs never rewrites such
+	// documents, they must be migrated by hand.
+	KindChanges bool
+	// Note explains what else changes with the replacement, if anything.
+	Note string
+}
+
+// DeprecatedAPIs are the deprecations that FindDeprecation knows. Please
+// help maintain the order by apiVersion.
+var DeprecatedAPIs = []DeprecatedAPI{
+	{
+		APIVersion:       "apps/v1beta1",
+		Kinds:            []string{"Deployment", "StatefulSet"},
+		DeprecatedIn:     "v1.9",
+		RemovedIn:        "v1.16",
+		Replacement:      "apps/v1",
+		RequiresSelector: true,
+		Note:             "apps/v1 requires spec.selector, and it must match spec.template.metadata.labels",
+	},
+	{
+		APIVersion:   "apps/v1beta2",
+		Kinds:        []string{"DaemonSet", "Deployment", "ReplicaSet", "StatefulSet"},
+		DeprecatedIn: "v1.9",
+		RemovedIn:    "v1.16",
+		Replacement:  "apps/v1",
+	},
+	{
+		APIVersion:   "batch/v2alpha1",
+		Kinds:        []string{"CronJob"},
+		DeprecatedIn: "v1.8",
+		Replacement:  "batch/v1beta1",
+	},
+	{
+		APIVersion:       "extensions/v1beta1",
+		Kinds:            []string{"DaemonSet", "Deployment", "ReplicaSet"},
+		DeprecatedIn:     "v1.9",
+		RemovedIn:        "v1.16",
+		Replacement:      "apps/v1",
+		RequiresSelector: true,
+		Note:             "apps/v1 requires spec.selector, and it must match spec.template.metadata.labels",
+	},
+	{
+		APIVersion:   "extensions/v1beta1",
+		Kinds:        []string{"Ingress"},
+		DeprecatedIn: "v1.14",
+		RemovedIn:    "v1.22",
+		Replacement:  "networking.k8s.io/v1beta1",
+	},
+	{
+		APIVersion:   "extensions/v1beta1",
+		Kinds:        []string{"NetworkPolicy"},
+		DeprecatedIn: "v1.9",
+		RemovedIn:    "v1.16",
+		Replacement:  "networking.k8s.io/v1",
+	},
+	{
+		APIVersion:   "extensions/v1beta1",
+		Kinds:        []string{"PodSecurityPolicy"},
+		DeprecatedIn: "v1.11",
+		RemovedIn:    "v1.16",
+		Replacement:  "policy/v1beta1",
+	},
+	{
+		APIVersion:   "extensions/v1beta1",
+		Kinds:        []string{"ThirdPartyResource"},
+		DeprecatedIn: "v1.7",
+		RemovedIn:    "v1.8",
+		Replacement:  "apiextensions.k8s.io/v1beta1",
+		KindChanges:  true,
+		Note:         "ThirdPartyResources are replaced by the CustomResourceDefinition kind, which has a different spec",
+	},
+	{
+		APIVersion:   "rbac.authorization.k8s.io/v1alpha1",
+		DeprecatedIn: "v1.8",
+		Replacement:  "rbac.authorization.k8s.io/v1",
+	},
+	{
+		APIVersion:   "rbac.authorization.k8s.io/v1beta1",
+		DeprecatedIn: "v1.8",
+		Replacement:  "rbac.authorization.k8s.io/v1",
+	},
+	{
+		APIVersion:   "storage.k8s.io/v1beta1",
+		Kinds:        []string{"StorageClass"},
+		DeprecatedIn: "v1.6",
+		Replacement:  "storage.k8s.io/v1",
+	},
+}
+
+// CompareReleases compares releases like "v1.9" and "v1.10" by number.
+func CompareReleases(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// FindDeprecation returns the deprecation of apiVersion and kind as of the
+// target release, or nil if they are not deprecated yet.
+func FindDeprecation(apiVersion, kind, target string) *DeprecatedAPI {
+	for i, d := range DeprecatedAPIs {
+		if d.APIVersion != apiVersion || CompareReleases(d.DeprecatedIn, target) > 0 {
+			continue
+		}
+		if len(d.Kinds) == 0 {
+			return &DeprecatedAPIs[i]
+		}
+		for _, k := range d.Kinds {
+			if k == kind {
+				return &DeprecatedAPIs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Removed reports whether d is removed as of the target release.
+func (d *DeprecatedAPI) Removed(target string) bool {
+	return d.RemovedIn != "" && CompareReleases(d.RemovedIn, target) <= 0
+}
+
+// String describes the deprecation and its replacement.
+func (d *DeprecatedAPI) String() string {
+	s := "deprecated since " + d.DeprecatedIn
+	if d.RemovedIn != "" {
+		s += " and removed in " + d.RemovedIn
+	}
+	s += fmt.Sprintf(". Use %q instead", d.Replacement)
+	if d.Note != "" {
+		s += "; " + d.Note
+	}
+	return s
+}
+
+// To catch the top-level apiVersion and kind of a YAML document, and the
+// document separators
+var topLevelAPIVersionRegexp = regexp.MustCompile(`(?m)^apiVersion:[ \t]*["']?([^"'\s#]+)["']?`)
+var topLevelKindRegexp = regexp.MustCompile(`(?m)^kind:[ \t]*["']?([^"'\s#]+)`)
+var documentSeparatorRegexp = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// To catch the apiVersion of a JSON document
+var jsonAPIVersionRegexp = regexp.MustCompile(`("apiVersion"\s*:\s*")([^"]+)(")`)
+
+// hasSelector reports whether a document of an example, in JSON, sets
+// spec.selector.
+func hasSelector(data []byte) bool {
+	var obj struct {
+		Spec struct {
+			Selector json.RawMessage `json:"selector"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return false
+	}
+	return len(obj.Spec.Selector) > 0 && string(obj.Spec.Selector) != "null"
+}
+
+// FixDeprecatedAPIs replaces the apiVersion of every document of an example
+// that uses a deprecated API as of the target release with its replacement.
+// Only the apiVersion is changed, so comments and formatting are kept. A
+// document whose replacement requires a spec.selector it does not have, or
+// whose kind changes, is left as is, since the result would be invalid, and
+// reported in skipped. It
+// returns the new content and whether anything was replaced.
+func FixDeprecatedAPIs(data []byte, isJSON bool, target string) (out []byte, fixed bool, skipped []string) {
+	// fixable reports whether the document can be rewritten for d.
+	fixable := func(i int, doc []byte, d *DeprecatedAPI, apiVersion, kind string) bool {
+		if d.KindChanges {
+			skipped = append(skipped, fmt.Sprintf("document %d: %s %s cannot be rewritten to %s, migrate it by hand: %s", i+1, apiVersion, kind, d.Replacement, d.Note))
+			return false
+		}
+		if !d.RequiresSelector || hasSelector(doc) {
+			return true
+		}
+		skipped = append(skipped, fmt.Sprintf("document %d: %s %s has no spec.selector, which %s requires, add it by hand", i+1, apiVersion, kind, d.Replacement))
+		return false
+	}
+
+	if isJSON {
+		var meta struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return data, false, nil
+		}
+		d := FindDeprecation(meta.APIVersion, meta.Kind, target)
+		if d == nil || !fixable(0, data, d, meta.APIVersion, meta.Kind) {
+			return data, false, skipped
+		}
+		loc := jsonAPIVersionRegexp.FindSubmatchIndex(data)
+		if loc == nil {
+			return data, false, nil
+		}
+		return append(append(append([]byte{}, data[:loc[4]]...), d.Replacement...), data[loc[5]:]...), true, nil
+	}
+
+	start := 0
+	bounds := append(documentSeparatorRegexp.FindAllIndex(data, -1), []int{len(data), len(data)})
+	for i, sep := range bounds {
+		doc := data[start:sep[0]]
+		if kind := topLevelKindRegexp.FindSubmatch(doc); kind != nil {
+			if loc := topLevelAPIVersionRegexp.FindSubmatchIndex(doc); loc != nil {
+				apiVersion := string(doc[loc[2]:loc[3]])
+				if d := FindDeprecation(apiVersion, string(kind[1]), target); d != nil {
+					converted, err := yaml.ToJSON(doc)
+					if err == nil && fixable(i, converted, d, apiVersion, string(kind[1])) {
+						doc = append(append(append([]byte{}, doc[:loc[2]]...), d.Replacement...), doc[loc[3]:]...)
+						fixed = true
+					}
+				}
+			}
+		}
+		out = append(append(out, doc...), data[sep[0]:sep[1]]...)
+		start = sep[1]
+	}
+	return out, fixed, skipped
+}
+
+// FixFile rewrites the example at path with FixDeprecatedAPIs. It reports
+// whether it changed, and the documents that were skipped.
+func FixFile(path, target string) (bool, []string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, nil, err
+	}
+	out, fixed, skipped := FixDeprecatedAPIs(data, filepath.Ext(path) == ".json", target)
+	if !fixed {
+		return false, skipped, nil
+	}
+	return true, skipped, ioutil.WriteFile(path, out, 0644)
+}
+
+// ReadSchemaVersions returns the releases listed in file, newest first, like
+// test/schemas/versions. The first one is the release of the docs.
+func ReadSchemaVersions(file string) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<version> <git tag>\", got %q", file, i+1, line)
+		}
+		versions = append(versions, fields[0])
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%s lists no versions", file)
+	}
+	return versions, nil
+}
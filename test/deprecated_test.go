@@ -0,0 +1,174 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	examples "k8s.io/website/test"
+)
+
+var deprecationTarget = flag.String("deprecation-target", "", "the release to check the examples for deprecated APIs against, e.g. v1.16 (default the first release of schemas/versions)")
+var fixDeprecated = flag.Bool("fix-deprecated", false, "rewrite the apiVersion of examples that use a deprecated API in place")
+
+// Flags the examples that use an API that is deprecated as of the target
+// release, and fails for APIs that are removed. With -fix-deprecated, the
+// examples are rewritten to use the replacement.
+func TestDeprecatedAPIs(t *testing.T) {
+	target := *deprecationTarget
+	if target == "" {
+		versions, err := examples.ReadSchemaVersions(schemaVersionsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		target = versions[0]
+	}
+	ignore, err := examples.ReadIgnore(examples.IgnoreFile, "..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, root := range exampleRoots {
+		err := examples.Walk(root, ignore, func(f *examples.File) {
+			deprecated := false
+			for i, doc := range f.Documents {
+				var meta struct {
+					APIVersion string `json:"apiVersion"`
+					Kind       string `json:"kind"`
+				}
+				if err := json.Unmarshal(doc.JSON, &meta); err != nil {
+					continue // reported by TestExampleObjectSchemas
+				}
+				d := examples.FindDeprecation(meta.APIVersion, meta.Kind, target)
+				if d == nil {
+					continue
+				}
+				deprecated = true
+				msg := fmt.Sprintf("%s: document %d uses %s %s, which is %v", f.Path, i+1, meta.APIVersion, meta.Kind, d)
+				if d.Removed(target) && !*fixDeprecated {
+					t.Error(msg)
+				} else {
+					t.Log(msg)
+				}
+			}
+			if !deprecated || !*fixDeprecated {
+				return
+			}
+			fixed, skipped, err := examples.FixFile(f.Path, target)
+			if err != nil {
+				t.Errorf("%s: %v", f.Path, err)
+				return
+			}
+			if fixed {
+				t.Logf("%s: rewritten, please check the notes above", f.Path)
+			}
+			for _, msg := range skipped {
+				t.Errorf("%s: not rewritten, %s", f.Path, msg)
+			}
+		})
+		if err != nil {
+			t.Errorf("Expected no error, Got %v on Path %v", err, root)
+		}
+	}
+}
+
+func TestFixDeprecatedAPIs(t *testing.T) {
+	yaml := `# A deployment
+apiVersion: extensions/v1beta1 # old
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  selector:
+    matchLabels:
+      app: nginx
+---
+apiVersion: v1
+kind: Service
+---
+apiVersion: "extensions/v1beta1"
+kind: Ingress
+`
+	want := `# A deployment
+apiVersion: apps/v1 # old
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  selector:
+    matchLabels:
+      app: nginx
+---
+apiVersion: v1
+kind: Service
+---
+apiVersion: "extensions/v1beta1"
+kind: Ingress
+`
+	got, fixed, skipped := examples.FixDeprecatedAPIs([]byte(yaml), false, "v1.10")
+	if !fixed || len(skipped) > 0 || string(got) != want {
+		t.Errorf("unexpected rewrite (%v, %q):\n%s\nwant:\n%s", fixed, skipped, got, want)
+	}
+	if _, fixed, _ := examples.FixDeprecatedAPIs([]byte(yaml), false, "v1.8"); fixed {
+		t.Errorf("expected extensions/v1beta1 Deployments not to be deprecated in v1.8")
+	}
+	got, _, _ = examples.FixDeprecatedAPIs([]byte(yaml), false, "v1.16")
+	if !strings.Contains(string(got), "apiVersion: \"networking.k8s.io/v1beta1\"\nkind: Ingress\n") {
+		t.Errorf("expected the Ingress to be rewritten for v1.16:\n%s", got)
+	}
+
+	// apps/v1 requires the selector that extensions/v1beta1 defaulted
+	noSelector := "apiVersion: v1\nkind: Service\n---\napiVersion: extensions/v1beta1\nkind: DaemonSet\nspec:\n  template:\n    metadata:\n      labels:\n        app: fluentd\n"
+	got, fixed, skipped = examples.FixDeprecatedAPIs([]byte(noSelector), false, "v1.10")
+	if fixed || string(got) != noSelector {
+		t.Errorf("expected a DaemonSet without a selector not to be rewritten, got:\n%s", got)
+	}
+	if want := "document 2: extensions/v1beta1 DaemonSet has no spec.selector, which apps/v1 requires, add it by hand"; len(skipped) != 1 || skipped[0] != want {
+		t.Errorf("unexpected skipped documents %q, want %q", skipped, want)
+	}
+	if _, fixed, skipped := examples.FixDeprecatedAPIs([]byte(`{"kind": "ReplicaSet", "apiVersion": "extensions/v1beta1", "spec": {}}`), true, "v1.10"); fixed || len(skipped) != 1 {
+		t.Errorf("expected a JSON ReplicaSet without a selector to be skipped, got %v, %q", fixed, skipped)
+	}
+
+	// a ThirdPartyResource becomes a CustomResourceDefinition with another spec
+	tpr := "apiVersion: extensions/v1beta1\nkind: ThirdPartyResource\nmetadata:\n  name: cron-tab.stable.example.com\n"
+	got, fixed, skipped = examples.FixDeprecatedAPIs([]byte(tpr), false, "v1.8")
+	if fixed || string(got) != tpr {
+		t.Errorf("expected a ThirdPartyResource not to be rewritten, got:\n%s", got)
+	}
+	if len(skipped) != 1 || !strings.HasPrefix(skipped[0], "document 1: extensions/v1beta1 ThirdPartyResource cannot be rewritten to apiextensions.k8s.io/v1beta1, migrate it by hand") {
+		t.Errorf("unexpected skipped documents %q", skipped)
+	}
+
+	got, fixed, _ = examples.FixDeprecatedAPIs([]byte(`{"kind": "StorageClass", "apiVersion": "storage.k8s.io/v1beta1"}`), true, "v1.10")
+	if want := `{"kind": "StorageClass", "apiVersion": "storage.k8s.io/v1"}`; !fixed || string(got) != want {
+		t.Errorf("unexpected rewrite (%v): %s, want %s", fixed, got, want)
+	}
+
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{{"v1.9", "v1.10", -1}, {"v1.10", "v1.10", 0}, {"v1.16", "v1.9", 1}} {
+		if got := examples.CompareReleases(c.a, c.b); got != c.want {
+			t.Errorf("examples.CompareReleases(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -14,4 +14,243 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
+// Package examples finds, decodes and validates the example manifests of the
+// docs, and checks them for deprecated APIs, best practices and dangling
+// references. The tests of this directory and the example-lint command use
+// it.
 package examples
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/kubernetes/pkg/api/legacyscheme"
+)
+
+// IgnoreFile lists the YAML and JSON files below the docs that are not
+// validated, one glob per line, relative to the root of the site and followed
+// by "# <reason>". A glob that matches a directory ignores all files below it.
+const IgnoreFile = "examples.ignore"
+
+// Ignore is a parsed IgnoreFile.
+type Ignore struct {
+	Patterns []string
+	Reasons  map[string]string
+	// root is the root of the site.
+	root string
+	// used records the patterns that matched a file.
+	used map[string]bool
+}
+
+// ReadIgnore reads an IgnoreFile whose globs are relative to root.
+func ReadIgnore(file, root string) (*Ignore, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	ig := &Ignore{Reasons: map[string]string{}, root: root, used: map[string]bool{}}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var reason string
+		if j := strings.Index(line, "#"); j >= 0 {
+			line, reason = strings.TrimSpace(line[:j]), strings.TrimSpace(line[j+1:])
+		}
+		pattern := filepath.FromSlash(line)
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, i+1, err)
+		}
+		if reason == "" {
+			return nil, fmt.Errorf("%s:%d: add the reason why %q is ignored after a \"#\"", file, i+1, line)
+		}
+		ig.Patterns = append(ig.Patterns, pattern)
+		ig.Reasons[pattern] = reason
+	}
+	return ig, nil
+}
+
+// Match returns the pattern that ignores the file at path, or "" if it is
+// not ignored. A nil Ignore ignores nothing.
+func (ig *Ignore) Match(path string) string {
+	if ig == nil {
+		return ""
+	}
+	root, err := filepath.Abs(ig.root)
+	if err != nil {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return ""
+	}
+	for _, pattern := range ig.Patterns {
+		for p := rel; p != "." && p != string(filepath.Separator); p = filepath.Dir(p) {
+			if ok, _ := filepath.Match(pattern, p); ok {
+				ig.used[pattern] = true
+				return pattern
+			}
+		}
+	}
+	return ""
+}
+
+// Used reports whether pattern matched a file.
+func (ig *Ignore) Used(pattern string) bool {
+	return ig.used[pattern]
+}
+
+// Document is a document of an example file.
+type Document struct {
+	// JSON is the document converted to JSON.
+	JSON []byte
+	// Node is the node tree of the document, to find the position of its
+	// fields.
+	Node *Node
+	// Line is the line number of the document in its file.
+	Line int
+}
+
+// File is an example file.
+type File struct {
+	Path      string
+	Documents []Document
+}
+
+// JSON returns the documents of f converted to JSON.
+func (f *File) JSON() [][]byte {
+	var docs [][]byte
+	for _, doc := range f.Documents {
+		docs = append(docs, doc.JSON)
+	}
+	return docs
+}
+
+// IsExample reports whether the file at path is an example, by its
+// extension.
+func IsExample(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".json" || ext == ".yaml"
+}
+
+// ReadFile reads an example, and splits it into documents. Documents that
+// are empty, e.g. only comments, are left out.
+func ReadFile(path string) (*File, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// workaround for Jekyllr limit
+	if bytes.HasPrefix(data, []byte("---\n")) {
+		return nil, fmt.Errorf("%s: YAML file cannot start with \"---\", please remove the first line", path)
+	}
+
+	f := &File{Path: path}
+	if filepath.Ext(path) != ".yaml" {
+		f.Documents = append(f.Documents, Document{JSON: data, Node: ParseNode(string(data), 1, 0), Line: 1})
+		return f, nil
+	}
+	// YAML can contain multiple documents.
+	splitter := yaml.NewYAMLReader(bufio.NewReader(bytes.NewBuffer(data)))
+	line := 1
+	for {
+		doc, err := splitter.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		out, err := yaml.ToJSON(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		// deal with "empty" document (e.g. pure comments)
+		if string(out) != "null" {
+			f.Documents = append(f.Documents, Document{JSON: out, Node: ParseNode(string(doc), line, 0), Line: line})
+		}
+		// the next document starts after the separator
+		line += bytes.Count(doc, []byte("\n")) + 1
+	}
+	return f, nil
+}
+
+// Walk walks dir and its subdirectories for any json/yaml files that ignore
+// does not match, and calls fn for each file found.
+func Walk(dir string, ignore *Ignore, fn func(f *File)) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !IsExample(path) || ignore.Match(path) != "" {
+			return nil
+		}
+		f, err := ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fn(f)
+		return nil
+	})
+}
+
+// Decode decodes a document of an example into the internal type of its
+// apiVersion and kind. It also returns the apiVersion and kind.
+func Decode(data []byte) (runtime.Object, *schema.GroupVersionKind, error) {
+	return legacyscheme.Codecs.UniversalDecoder().Decode(data, nil, nil)
+}
+
+// FormatKind formats gvk as "<apiVersion> <kind>".
+func FormatKind(gvk schema.GroupVersionKind) string {
+	return gvk.GroupVersion().String() + " " + gvk.Kind
+}
+
+// ExpectedKindsExt is the extension of the optional sidecar file of an
+// example, e.g. nginx-app.kinds for nginx-app.yaml. It lists the apiVersion
+// and kind of each document of the example in order, one per line:
+//
+//	v1 Service
+//	apps/v1 Deployment
+//
+// Lines starting with "#" are ignored.
+const ExpectedKindsExt = ".kinds"
+
+// ReadExpectedKinds returns the kinds listed in the sidecar file of the
+// example at path, or nil if it has none.
+func ReadExpectedKinds(path string) ([]schema.GroupVersionKind, error) {
+	sidecar := strings.TrimSuffix(path, filepath.Ext(path)) + ExpectedKindsExt
+	data, err := ioutil.ReadFile(sidecar)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	kinds := []schema.GroupVersionKind{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<apiVersion> <kind>\", got %q", sidecar, i+1, line)
+		}
+		kinds = append(kinds, schema.FromAPIVersionAndKind(fields[0], fields[1]))
+	}
+	return kinds, nil
+}
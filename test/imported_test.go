@@ -0,0 +1,60 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/website/update-imported-docs/marker"
+)
+
+// Checks that the files written by update-imported-docs, which carry a
+// "DO NOT EDIT" marker, have not been edited by hand since they were
+// imported. The next import would silently drop such edits.
+func TestImportedFilesUnchanged(t *testing.T) {
+	for _, root := range []string{"../docs", "../_data"} {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			switch filepath.Ext(path) {
+			case ".md", ".html", ".yml", ".yaml":
+			default:
+				return nil
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if stamped, unchanged := marker.Check(content); stamped && !unchanged {
+				t.Errorf("%s was changed after it was imported by update-imported-docs, and the next import will overwrite the change. "+
+					"Revert it, and make the change in the upstream repo the file is imported from instead "+
+					"(see the src of this file in update-imported-docs/*.yml). Only the front matter of an imported file may be edited here.", path)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unable to walk %s: %v", root, err)
+		}
+	}
+}
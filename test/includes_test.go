@@ -0,0 +1,128 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	examples "k8s.io/website/test"
+)
+
+// To catch {% include code.html ... %} tags, and the parameters of one
+var codeIncludeRegexp = regexp.MustCompile(`\{%-?\s*include\s+code\.html\s+(.*?)-?%\}`)
+var includeParamRegexp = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|'([^']*)')`)
+
+// codeInclude is a {% include code.html %} tag of a page.
+type codeInclude struct {
+	line   int
+	params map[string]string
+}
+
+// codeIncludes returns the code.html includes of a page.
+func codeIncludes(data string) []codeInclude {
+	var includes []codeInclude
+	for i, line := range strings.Split(data, "\n") {
+		for _, m := range codeIncludeRegexp.FindAllStringSubmatch(line, -1) {
+			include := codeInclude{line: i + 1, params: map[string]string{}}
+			for _, p := range includeParamRegexp.FindAllStringSubmatch(m[1], -1) {
+				include.params[p[1]] = p[2] + p[3]
+			}
+			includes = append(includes, include)
+		}
+	}
+	return includes
+}
+
+// isValidatedExample reports whether TestExampleObjectSchemas validates the
+// file at path, which is relative to this directory, unless it is ignored.
+func isValidatedExample(path string) bool {
+	if ext := filepath.Ext(path); ext != ".yaml" && ext != ".json" {
+		return false
+	}
+	for _, root := range exampleRoots {
+		if strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks that the files embedded with {% include code.html file=... %} exist
+// relative to the page, that their ghlink points at the same file, and that
+// embedded YAML and JSON files are validated by TestExampleObjectSchemas.
+func TestCodeIncludes(t *testing.T) {
+	ignore, err := examples.ReadIgnore(examples.IgnoreFile, "..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range exampleRoots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if ext := filepath.Ext(path); info.IsDir() || (ext != ".md" && ext != ".html") {
+				return nil
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, include := range codeIncludes(string(data)) {
+				file := include.params["file"]
+				if file == "" {
+					t.Errorf("%s:%d: code.html is included without a file", path, include.line)
+					continue
+				}
+				// include_relative resolves the file relative to the page
+				example := filepath.Join(filepath.Dir(path), filepath.FromSlash(file))
+				if info, err := os.Stat(example); err != nil || info.IsDir() {
+					t.Errorf("%s:%d: the included file %q does not exist at %s", path, include.line, file, example)
+					continue
+				}
+				rel, err := filepath.Rel("..", example)
+				if err != nil {
+					return err
+				}
+				want := "/" + filepath.ToSlash(rel)
+				if ghlink, ok := include.params["ghlink"]; ok && ghlink != want {
+					t.Errorf("%s:%d: the ghlink %q of the included file %q should be %q", path, include.line, ghlink, file, want)
+				}
+				ext := filepath.Ext(example)
+				if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+					continue
+				}
+				if pattern := ignore.Match(example); pattern != "" {
+					t.Logf("%s:%d: the included file %s is not validated: %s", path, include.line, example, ignore.Reasons[pattern])
+					continue
+				}
+				if !isValidatedExample(example) {
+					t.Errorf("%s:%d: the included file %s is not validated. Rename it to .yaml, or move it below %s",
+						path, include.line, example, strings.Join(exampleRoots, " or "))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Unable to walk %s: %v", root, err)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// LintDisableAnnotation suppresses lint rules for an object. Its value is a
+// comma-separated list of rule IDs, e.g. "latest-image,host-path", or "all".
+const LintDisableAnnotation = "website.k8s.io/lint-disable"
+
+// Severity is how serious a problem is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Object is a document of an example, decoded without a scheme.
+type Object map[string]interface{}
+
+// Rule is a best practice that examples should follow.
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+	// check returns a message for each problem of objs[i]. objs are all the
+	// documents of the example.
+	check func(objs []Object, i int) []string
+}
+
+// Rules are the best practices that Lint checks. Please help maintain the
+// order by ID.
+var Rules = []Rule{
+	{
+		ID:          "host-path",
+		Severity:    SeverityWarning,
+		Description: "hostPath volumes tie a Pod to a node and expose the node's filesystem",
+		check:       checkHostPath,
+	},
+	{
+		ID:          "latest-image",
+		Severity:    SeverityWarning,
+		Description: "images should have a tag other than latest, so that the example keeps working the same way",
+		check:       checkLatestImage,
+	},
+	{
+		ID:          "no-probes",
+		Severity:    SeverityWarning,
+		Description: "the containers of a Deployment should have liveness and readiness probes",
+		check:       checkProbes,
+	},
+	{
+		ID:          "no-resources",
+		Severity:    SeverityWarning,
+		Description: "containers should have resource requests and limits",
+		check:       checkResources,
+	},
+	{
+		ID:          "unmatched-selector",
+		Severity:    SeverityError,
+		Description: "the selector of a Service should match a Pod template of the same example",
+		check:       checkServiceSelector,
+	},
+}
+
+// Finding is a problem found by a lint rule.
+type Finding struct {
+	Rule *Rule
+	// Doc is the index of the document in the example.
+	Doc     int
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("document %d: %s [%s] %s", f.Doc+1, f.Rule.Severity, f.Rule.ID, f.Message)
+}
+
+// Get returns the value at path in o, or nil.
+func (o Object) Get(path ...string) interface{} {
+	var v interface{} = map[string]interface{}(o)
+	for _, p := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[p]
+	}
+	return v
+}
+
+// GetMap returns the mapping at path in o, or nil.
+func (o Object) GetMap(path ...string) map[string]interface{} {
+	m, _ := o.Get(path...).(map[string]interface{})
+	return m
+}
+
+// GetString returns the string at path in o, or "".
+func (o Object) GetString(path ...string) string {
+	s, _ := o.Get(path...).(string)
+	return s
+}
+
+// Name returns the kind and name of o for messages.
+func (o Object) Name() string {
+	return fmt.Sprintf("%s %q", o.GetString("kind"), o.GetString("metadata", "name"))
+}
+
+// Disabled reports whether the rule is suppressed by LintDisableAnnotation.
+func (o Object) Disabled(rule string) bool {
+	for _, id := range strings.Split(o.GetString("metadata", "annotations", LintDisableAnnotation), ",") {
+		if id = strings.TrimSpace(id); id == rule || id == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// PodTemplate returns the Pod spec and labels of o, if it is a Pod or holds
+// a Pod template.
+func (o Object) PodTemplate() (spec, labels map[string]interface{}, ok bool) {
+	var template Object
+	switch o.GetString("kind") {
+	case "Pod":
+		template = o
+	case "CronJob":
+		template = Object(o.GetMap("spec", "jobTemplate", "spec", "template"))
+	case "DaemonSet", "Deployment", "Job", "ReplicaSet", "ReplicationController", "StatefulSet":
+		template = Object(o.GetMap("spec", "template"))
+	default:
+		return nil, nil, false
+	}
+	return template.GetMap("spec"), template.GetMap("metadata", "labels"), true
+}
+
+// Containers returns the containers of the Pod template of o, including init
+// containers if init is set.
+func (o Object) Containers(init bool) []Object {
+	spec, _, ok := o.PodTemplate()
+	if !ok {
+		return nil
+	}
+	fields := []string{"containers"}
+	if init {
+		fields = append(fields, "initContainers")
+	}
+	var containers []Object
+	for _, f := range fields {
+		list, _ := spec[f].([]interface{})
+		for _, c := range list {
+			if m, ok := c.(map[string]interface{}); ok {
+				containers = append(containers, Object(m))
+			}
+		}
+	}
+	return containers
+}
+
+func checkHostPath(objs []Object, i int) []string {
+	spec, _, ok := objs[i].PodTemplate()
+	if !ok {
+		return nil
+	}
+	var msgs []string
+	volumes, _ := spec["volumes"].([]interface{})
+	for _, v := range volumes {
+		volume := Object(toMap(v))
+		if volume.Get("hostPath") != nil {
+			msgs = append(msgs, fmt.Sprintf("%s uses the hostPath volume %q", objs[i].Name(), volume.GetString("name")))
+		}
+	}
+	return msgs
+}
+
+func checkLatestImage(objs []Object, i int) []string {
+	var msgs []string
+	for _, c := range objs[i].Containers(true) {
+		image := c.GetString("image")
+		if image == "" || strings.Contains(image, "@") || strings.Contains(image, "{{") {
+			continue
+		}
+		// The tag follows the last colon after the last slash; a colon
+		// before it belongs to a registry port.
+		tag := ""
+		if j := strings.LastIndex(image, ":"); j > strings.LastIndex(image, "/") {
+			tag = image[j+1:]
+		}
+		switch tag {
+		case "":
+			msgs = append(msgs, fmt.Sprintf("the image %q of container %q of %s has no tag", image, c.GetString("name"), objs[i].Name()))
+		case "latest":
+			msgs = append(msgs, fmt.Sprintf("the image %q of container %q of %s uses the latest tag", image, c.GetString("name"), objs[i].Name()))
+		}
+	}
+	return msgs
+}
+
+func checkProbes(objs []Object, i int) []string {
+	if objs[i].GetString("kind") != "Deployment" {
+		return nil
+	}
+	var msgs []string
+	for _, c := range objs[i].Containers(false) {
+		var missing []string
+		for _, probe := range []string{"livenessProbe", "readinessProbe"} {
+			if c.Get(probe) == nil {
+				missing = append(missing, probe)
+			}
+		}
+		if len(missing) > 0 {
+			msgs = append(msgs, fmt.Sprintf("container %q of %s has no %s", c.GetString("name"), objs[i].Name(), strings.Join(missing, " or ")))
+		}
+	}
+	return msgs
+}
+
+func checkResources(objs []Object, i int) []string {
+	var msgs []string
+	for _, c := range objs[i].Containers(false) {
+		var missing []string
+		for _, r := range []string{"requests", "limits"} {
+			if len(c.GetMap("resources", r)) == 0 {
+				missing = append(missing, r)
+			}
+		}
+		if len(missing) > 0 {
+			msgs = append(msgs, fmt.Sprintf("container %q of %s has no resource %s", c.GetString("name"), objs[i].Name(), strings.Join(missing, " or ")))
+		}
+	}
+	return msgs
+}
+
+// checkServiceSelector only checks examples that have Pod templates, since
+// many examples show a Service on its own.
+func checkServiceSelector(objs []Object, i int) []string {
+	selector := objs[i].GetMap("spec", "selector")
+	if objs[i].GetString("kind") != "Service" || len(selector) == 0 {
+		return nil
+	}
+	templates := 0
+	for _, o := range objs {
+		if _, labels, ok := o.PodTemplate(); ok {
+			templates++
+			if matchesSelector(selector, labels) {
+				return nil
+			}
+		}
+	}
+	if templates == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("the selector %s of %s matches no Pod template of the example", formatSelector(selector), objs[i].Name())}
+}
+
+// matchesSelector reports whether labels has all the labels of selector.
+func matchesSelector(selector, labels map[string]interface{}) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func formatSelector(selector map[string]interface{}) string {
+	data, _ := json.Marshal(selector)
+	return string(data)
+}
+
+func toMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// EnabledRules returns the rules with the comma-separated IDs, or all rules
+// if ids is empty.
+func EnabledRules(ids string) ([]*Rule, error) {
+	var rules []*Rule
+	if ids == "" {
+		for i := range Rules {
+			rules = append(rules, &Rules[i])
+		}
+		return rules, nil
+	}
+	for _, id := range strings.Split(ids, ",") {
+		found := false
+		for i := range Rules {
+			if Rules[i].ID == strings.TrimSpace(id) {
+				rules = append(rules, &Rules[i])
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown lint rule %q", id)
+		}
+	}
+	return rules, nil
+}
+
+// Lint runs the rules on the documents of an example, which are JSON.
+// Documents that cannot be decoded are skipped.
+func Lint(docs [][]byte, rules []*Rule) []Finding {
+	objs := make([]Object, len(docs))
+	for i, data := range docs {
+		json.Unmarshal(data, &objs[i])
+	}
+	var findings []Finding
+	for _, rule := range rules {
+		for i, obj := range objs {
+			if obj == nil || obj.Disabled(rule.ID) {
+				continue
+			}
+			for _, msg := range rule.check(objs, i) {
+				findings = append(findings, Finding{Rule: rule, Doc: i, Message: msg})
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples_test
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	examples "k8s.io/website/test"
+)
+
+var lintRuleIDs = flag.String("lint-rules", "", "comma-separated IDs of the lint rules to run (default all)")
+
+// Lints the examples with the rules selected by -lint-rules. Findings with
+// error severity fail the test, warnings are logged.
+func TestLintExamples(t *testing.T) {
+	rules, err := examples.EnabledRules(*lintRuleIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ignore, err := examples.ReadIgnore(examples.IgnoreFile, "..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range exampleRoots {
+		err := examples.Walk(root, ignore, func(f *examples.File) {
+			for _, finding := range examples.Lint(f.JSON(), rules) {
+				if finding.Rule.Severity == examples.SeverityError {
+					t.Errorf("%s: %s", f.Path, finding)
+				} else {
+					t.Logf("%s: %s", f.Path, finding)
+				}
+			}
+		})
+		if err != nil {
+			t.Errorf("Expected no error, Got %v on Path %v", err, root)
+		}
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	docs := []string{
+		`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}, "spec": {"selector": {"app": "web"}}}`,
+		`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "db"}, "spec": {"selector": {"app": "db"}}}`,
+		`{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web"}, "spec": {"template": {
+			"metadata": {"labels": {"app": "web", "tier": "frontend"}},
+			"spec": {
+				"containers": [
+					{"name": "nginx", "image": "nginx", "livenessProbe": {}, "readinessProbe": {}},
+					{"name": "sidecar", "image": "registry:5000/sidecar:1.0", "resources": {"requests": {"cpu": "1"}, "limits": {"cpu": "1"}}}
+				],
+				"initContainers": [{"name": "init", "image": "busybox:latest"}],
+				"volumes": [{"name": "logs", "hostPath": {"path": "/var/log"}}]
+			}}}}`,
+		`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "debug", "annotations": {"` + examples.LintDisableAnnotation + `": "no-resources, latest-image"}},
+			"spec": {"containers": [{"name": "debug", "image": "busybox"}]}}`,
+	}
+	var data [][]byte
+	for _, doc := range docs {
+		data = append(data, []byte(doc))
+	}
+	rules, err := examples.EnabledRules("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range examples.Lint(data, rules) {
+		got = append(got, fmt.Sprintf("%d %s", f.Doc, f.Rule.ID))
+	}
+	want := []string{
+		"2 host-path",
+		"2 latest-image",
+		"2 latest-image",
+		"2 no-probes",
+		"2 no-resources",
+		"1 unmatched-selector",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := examples.EnabledRules("host-path,unknown"); err == nil {
+		t.Errorf("expected an error for an unknown rule")
+	}
+}
@@ -0,0 +1,356 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Node is a node of a YAML document with its position in the file. It only
+// keeps the structure needed to find where a field is defined. Block and flow
+// collections are supported, so JSON documents can be parsed too.
+type Node struct {
+	// Line and Column are 1-based. The position of a mapping value is the
+	// position of its key.
+	Line, Column int
+	Mapping      map[string]*Node
+	Items        []*Node
+}
+
+// yamlLine is a line of a document that is not blank or a comment.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// ParseNode returns the node tree of a YAML or JSON document whose
+// first line is firstLine in its file, and whose lines are indented by indent
+// columns in the file, e.g. in a Markdown list. It returns nil for an empty
+// document. Content it does not understand is left out of the tree.
+func ParseNode(doc string, firstLine, indent int) *Node {
+	p := &yamlParser{}
+	for i, text := range strings.Split(doc, "\n") {
+		trimmed := strings.TrimLeft(text, " ")
+		if t := strings.TrimSpace(trimmed); t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{num: firstLine + i, indent: len(text) - len(trimmed), text: strings.TrimRight(text, " \t\r")})
+	}
+	if len(p.lines) == 0 {
+		return nil
+	}
+	node := p.parseNode(p.lines[0].indent, -1)
+	shiftColumns(node, indent)
+	return node
+}
+
+func shiftColumns(n *Node, by int) {
+	if n == nil {
+		return
+	}
+	n.Column += by
+	for _, c := range n.Mapping {
+		shiftColumns(c, by)
+	}
+	for _, c := range n.Items {
+		shiftColumns(c, by)
+	}
+}
+
+// next moves to the next line.
+func (p *yamlParser) next() {
+	p.pos++
+}
+
+// peek returns the next line, if any.
+func (p *yamlParser) peek() (yamlLine, bool) {
+	if p.pos+1 < len(p.lines) {
+		return p.lines[p.pos+1], true
+	}
+	return yamlLine{}, false
+}
+
+// To catch the key of a block mapping entry, quoted or plain
+var blockKeyRegexp = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s"'{\[\-#?][^#]*?|-[^\s#][^#]*?)\s*:(?:\s|$)`)
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// stripComment removes a trailing comment from a value.
+func stripComment(text string) string {
+	quote := byte(0)
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimSpace(text[:i])
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+func unquote(key string) string {
+	if strings.HasPrefix(key, `"`) {
+		if s, err := strconv.Unquote(key); err == nil {
+			return s
+		}
+	}
+	if strings.HasPrefix(key, "'") && len(key) > 1 {
+		return strings.Replace(key[1:len(key)-1], "''", "'", -1)
+	}
+	return key
+}
+
+// parseNode parses the node that starts at column col of the current line.
+// parent is the indentation of the enclosing collection, or -1: following
+// lines indented more than it belong to the node.
+func (p *yamlParser) parseNode(col, parent int) *Node {
+	l := p.lines[p.pos]
+	text := l.text[col:]
+	node := &Node{Line: l.num, Column: col + 1}
+	switch {
+	case isSequenceItem(text):
+		for {
+			rest := strings.TrimLeft(text[1:], " ")
+			var item *Node
+			if stripComment(rest) == "" {
+				if next, ok := p.peek(); ok && next.indent > col {
+					p.next()
+					item = p.parseNode(next.indent, col)
+				} else {
+					item = &Node{Line: l.num, Column: col + 1}
+				}
+			} else {
+				item = p.parseNode(col+len(text)-len(rest), col)
+			}
+			node.Items = append(node.Items, item)
+			next, ok := p.peek()
+			if !ok || next.indent != col || !isSequenceItem(next.text[col:]) {
+				return node
+			}
+			p.next()
+			l, text = next, next.text[col:]
+		}
+
+	case blockKeyRegexp.MatchString(text):
+		node.Mapping = map[string]*Node{}
+		for {
+			m := blockKeyRegexp.FindStringSubmatch(text)
+			rest := strings.TrimLeft(text[len(m[0]):], " ")
+			var value *Node
+			if stripComment(rest) == "" {
+				// The value is on the next lines, and a sequence may be
+				// indented as much as its key.
+				next, ok := p.peek()
+				if ok && (next.indent > col || next.indent == col && isSequenceItem(next.text[col:])) {
+					p.next()
+					value = p.parseNode(next.indent, col)
+				} else {
+					value = &Node{}
+				}
+			} else {
+				value = p.parseNode(col+len(text)-len(rest), col)
+			}
+			value.Line, value.Column = l.num, col+1
+			node.Mapping[unquote(strings.TrimSpace(m[1]))] = value
+			next, ok := p.peek()
+			if !ok || next.indent != col || !blockKeyRegexp.MatchString(next.text[col:]) {
+				return node
+			}
+			p.next()
+			l, text = next, next.text[col:]
+		}
+
+	case strings.HasPrefix(text, "{") || strings.HasPrefix(text, "["):
+		f := &flowScanner{p: p, col: col}
+		return f.parseValue()
+
+	default:
+		// A scalar, which may continue on the lines indented more than its
+		// parent, like a block scalar
+		for {
+			next, ok := p.peek()
+			if !ok || next.indent <= parent {
+				return node
+			}
+			p.next()
+		}
+	}
+}
+
+// flowScanner parses a flow collection, which may span several lines.
+type flowScanner struct {
+	p   *yamlParser
+	col int
+}
+
+// peek skips whitespace and returns the next character, or 0 at the end.
+func (f *flowScanner) peek() byte {
+	for f.p.pos < len(f.p.lines) {
+		text := f.p.lines[f.p.pos].text
+		for f.col < len(text) && (text[f.col] == ' ' || text[f.col] == '\t') {
+			f.col++
+		}
+		if f.col < len(text) {
+			return text[f.col]
+		}
+		if f.p.pos+1 == len(f.p.lines) {
+			return 0
+		}
+		f.p.pos++
+		f.col = 0
+	}
+	return 0
+}
+
+func (f *flowScanner) position() (int, int) {
+	return f.p.lines[f.p.pos].num, f.col + 1
+}
+
+// scalar reads a quoted or plain scalar.
+func (f *flowScanner) scalar(isKey bool) string {
+	text := f.p.lines[f.p.pos].text
+	start := f.col
+	if c := text[f.col]; c == '"' || c == '\'' {
+		for f.col++; f.col < len(text); f.col++ {
+			if text[f.col] == '\\' && c == '"' {
+				f.col++
+			} else if text[f.col] == c {
+				f.col++
+				break
+			}
+		}
+		return unquote(text[start:f.col])
+	}
+	for ; f.col < len(text); f.col++ {
+		c := text[f.col]
+		if c == ',' || c == ']' || c == '}' || isKey && c == ':' && (f.col+1 == len(text) || text[f.col+1] == ' ') {
+			break
+		}
+	}
+	return strings.TrimSpace(text[start:f.col])
+}
+
+func (f *flowScanner) parseValue() *Node {
+	c := f.peek()
+	line, column := f.position()
+	node := &Node{Line: line, Column: column}
+	switch c {
+	case '{':
+		node.Mapping = map[string]*Node{}
+		f.col++
+		for {
+			if c := f.peek(); c == '}' || c == 0 {
+				f.col++
+				return node
+			}
+			pos, col := f.p.pos, f.col
+			line, column := f.position()
+			key := f.scalar(true)
+			var value *Node
+			if f.peek() == ':' {
+				f.col++
+				value = f.parseValue()
+			} else {
+				value = &Node{}
+			}
+			value.Line, value.Column = line, column
+			node.Mapping[key] = value
+			if f.peek() == ',' || f.p.pos == pos && f.col == col {
+				// Skips a separator, or a character out of place.
+				f.col++
+			}
+		}
+	case '[':
+		f.col++
+		for {
+			if c := f.peek(); c == ']' || c == 0 {
+				f.col++
+				return node
+			}
+			pos, col := f.p.pos, f.col
+			node.Items = append(node.Items, f.parseValue())
+			if f.peek() == ',' || f.p.pos == pos && f.col == col {
+				f.col++
+			}
+		}
+	case 0:
+		return node
+	default:
+		f.scalar(false)
+		return node
+	}
+}
+
+// To catch the segments of a field path, e.g. "spec", "[0]" or "[app]"
+var fieldSegmentRegexp = regexp.MustCompile(`[^.\[\]]+|\[[^\]]*\]`)
+
+// Find returns the node of a field path of a validation error, e.g.
+// "spec.template.spec.containers[0].image", or the deepest node on that path
+// that exists.
+func (n *Node) Find(path string) *Node {
+	for _, seg := range fieldSegmentRegexp.FindAllString(path, -1) {
+		var child *Node
+		if strings.HasPrefix(seg, "[") {
+			seg = strings.Trim(seg, "[]")
+			if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(n.Items) {
+				child = n.Items[i]
+			}
+		}
+		if child == nil {
+			child = n.Mapping[seg]
+		}
+		if child == nil {
+			break
+		}
+		n = child
+	}
+	return n
+}
+
+// FormatFieldErrors formats each validation error of a document at the
+// position of its field, e.g. "pod.yaml:12:7: spec.containers[0].image:
+// Required value". Without a node tree, the errors are reported at line.
+func FormatFieldErrors(path string, node *Node, line int, errors field.ErrorList) []string {
+	var out []string
+	for _, err := range errors {
+		pos := fmt.Sprintf("%s:%d", path, line)
+		if node != nil {
+			n := node.Find(err.Field)
+			pos = fmt.Sprintf("%s:%d:%d", path, n.Line, n.Column)
+		}
+		out = append(out, fmt.Sprintf("%s: %v", pos, err))
+	}
+	return out
+}
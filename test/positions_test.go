@@ -0,0 +1,88 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	examples "k8s.io/website/test"
+)
+
+func TestYAMLPositions(t *testing.T) {
+	doc := strings.Join([]string{
+		"# A pod",                        // 10
+		"apiVersion: v1",                 // 11
+		"kind: Pod",                      // 12
+		"metadata:",                      // 13
+		"  name: web",                    // 14
+		"  labels: {app: web, \"tier\":", // 15
+		"    frontend}",                  // 16
+		"spec:",                          // 17
+		"  containers:",                  // 18
+		"  - name: nginx",                // 19
+		"    image: nginx:1.7.9",         // 20
+		"    command:",                   // 21
+		"    - sh",                       // 22
+		"    - -c",                       // 23
+		"    - |",                        // 24
+		"      echo a: b",                // 25
+		"",                               // 26
+		"      echo c",                   // 27
+		"  -",                            // 28
+		"    name: sidecar",              // 29
+		"    'image': busybox # tag?",    // 30
+		"  restartPolicy: Always",        // 31
+	}, "\n")
+	node := examples.ParseNode(doc, 10, 4)
+
+	for path, want := range map[string]string{
+		"kind":                          "12:5",
+		"metadata.name":                 "14:7",
+		"metadata.labels[app]":          "15:16",
+		"metadata.labels[tier]":         "15:26",
+		"spec.containers[0]":            "19:9",
+		"spec.containers[0].image":      "20:9",
+		"spec.containers[0].command[2]": "24:11",
+		"spec.containers[1].image":      "30:9",
+		"spec.containers[1].ports[0]":   "29:9",
+		"spec.containers[2]":            "18:7",
+		"spec.restartPolicy":            "31:7",
+		"spec.hostname":                 "17:5",
+	} {
+		n := node.Find(path)
+		if got := fmt.Sprintf("%d:%d", n.Line, n.Column); got != want {
+			t.Errorf("%s: got %s, want %s", path, got, want)
+		}
+	}
+
+	json := "{\n  \"kind\": \"Service\",\n  \"spec\": {\n    \"ports\": [{\"port\": 80}, {\"port\": 443}]\n  }\n}"
+	node = examples.ParseNode(json, 1, 0)
+	if n := node.Find("spec.ports[1].port"); n.Line != 4 || n.Column != 30 {
+		t.Errorf("spec.ports[1].port: got %d:%d, want 4:30", n.Line, n.Column)
+	}
+
+	errors := field.ErrorList{field.Required(field.NewPath("spec", "ports").Index(0).Child("port"), "")}
+	if got, want := examples.FormatFieldErrors("svc.json", node, 1, errors), "svc.json:4:16: spec.ports[0].port: Required value"; len(got) != 1 || got[0] != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := examples.FormatFieldErrors("svc.json", nil, 3, errors), "svc.json:3: spec.ports[0].port: Required value"; len(got) != 1 || got[0] != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
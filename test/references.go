@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Entry is a document of an example file, decoded without a scheme.
+type Entry struct {
+	Path string
+	// Doc is the index of the document in the file.
+	Doc    int
+	Object Object
+}
+
+func (e Entry) String() string {
+	return fmt.Sprintf("%s: document %d", e.Path, e.Doc+1)
+}
+
+// Entries returns the documents of f that decode.
+func (f *File) Entries() []Entry {
+	var entries []Entry
+	for i, doc := range f.Documents {
+		var obj Object
+		if err := json.Unmarshal(doc.JSON, &obj); err == nil && obj != nil {
+			entries = append(entries, Entry{Path: f.Path, Doc: i, Object: obj})
+		}
+	}
+	return entries
+}
+
+// Reference is a reference from an object to another one by name.
+type Reference struct {
+	Kind  string
+	Name  string
+	Field string
+}
+
+// Namespace returns the namespace of o, or "" if it is not set.
+func (o Object) Namespace() string {
+	return o.GetString("metadata", "namespace")
+}
+
+// References returns the objects o refers to by name: the PVCs, ConfigMaps
+// and Secrets of its Pod template, and the target of a
+// HorizontalPodAutoscaler. Optional references are left out.
+func (o Object) References() []Reference {
+	var refs []Reference
+	add := func(kind string, ref map[string]interface{}, nameField, field string) {
+		name, _ := ref[nameField].(string)
+		if optional, _ := ref["optional"].(bool); name != "" && !optional {
+			refs = append(refs, Reference{Kind: kind, Name: name, Field: field})
+		}
+	}
+
+	if o.GetString("kind") == "HorizontalPodAutoscaler" {
+		target := o.GetMap("spec", "scaleTargetRef")
+		add(Object(target).GetString("kind"), target, "name", "spec.scaleTargetRef")
+	}
+
+	spec, _, ok := o.PodTemplate()
+	if !ok {
+		return refs
+	}
+	volumes, _ := spec["volumes"].([]interface{})
+	for i, v := range volumes {
+		volume := Object(toMap(v))
+		field := fmt.Sprintf("volumes[%d]", i)
+		add("PersistentVolumeClaim", volume.GetMap("persistentVolumeClaim"), "claimName", field+".persistentVolumeClaim.claimName")
+		add("ConfigMap", volume.GetMap("configMap"), "name", field+".configMap.name")
+		add("Secret", volume.GetMap("secret"), "secretName", field+".secret.secretName")
+		sources, _ := volume.Get("projected", "sources").([]interface{})
+		for j, s := range sources {
+			source := Object(toMap(s))
+			sourceField := fmt.Sprintf("%s.projected.sources[%d]", field, j)
+			add("ConfigMap", source.GetMap("configMap"), "name", sourceField+".configMap.name")
+			add("Secret", source.GetMap("secret"), "name", sourceField+".secret.name")
+		}
+	}
+	secrets, _ := spec["imagePullSecrets"].([]interface{})
+	for i, s := range secrets {
+		add("Secret", toMap(s), "name", fmt.Sprintf("imagePullSecrets[%d].name", i))
+	}
+	for _, c := range o.Containers(true) {
+		field := fmt.Sprintf("container %q", c.GetString("name"))
+		env, _ := c["env"].([]interface{})
+		for _, e := range env {
+			v := Object(toMap(e))
+			add("ConfigMap", v.GetMap("valueFrom", "configMapKeyRef"), "name", field+" env "+v.GetString("name"))
+			add("Secret", v.GetMap("valueFrom", "secretKeyRef"), "name", field+" env "+v.GetString("name"))
+		}
+		envFrom, _ := c["envFrom"].([]interface{})
+		for i, e := range envFrom {
+			v := Object(toMap(e))
+			add("ConfigMap", v.GetMap("configMapRef"), "name", fmt.Sprintf("%s envFrom[%d]", field, i))
+			add("Secret", v.GetMap("secretRef"), "name", fmt.Sprintf("%s envFrom[%d]", field, i))
+		}
+	}
+	return refs
+}
+
+// ReferenceProblem is a reference of an object that does not resolve within
+// its example set.
+type ReferenceProblem struct {
+	From    Entry
+	Message string
+	// LikelyMistake is set if the file of the object defines objects of the
+	// referenced kind, but none with that name. Otherwise the object is
+	// probably created by another step of the page.
+	LikelyMistake bool
+}
+
+// CheckReferences resolves the references of a set of example objects, e.g.
+// the files of a directory, within the set. Objects that disable
+// RuleReference with LintDisableAnnotation are not checked. Service selectors
+// are checked by the unmatched-selector lint rule.
+func CheckReferences(set []Entry) []ReferenceProblem {
+	type key struct{ kind, namespace, name string }
+	defined := map[key]bool{}
+	// kinds holds the names of the objects of each file by kind.
+	kinds := map[string]map[string][]string{}
+	for _, o := range set {
+		kind := o.Object.GetString("kind")
+		defined[key{kind, o.Object.Namespace(), o.Object.GetString("metadata", "name")}] = true
+		if kinds[o.Path] == nil {
+			kinds[o.Path] = map[string][]string{}
+		}
+		kinds[o.Path][kind] = append(kinds[o.Path][kind], o.Object.GetString("metadata", "name"))
+		// A StatefulSet creates a PVC for each claim template and Pod.
+		claims, _ := o.Object.Get("spec", "volumeClaimTemplates").([]interface{})
+		for _, c := range claims {
+			defined[key{"PersistentVolumeClaim", o.Object.Namespace(), Object(toMap(c)).GetString("metadata", "name")}] = true
+		}
+	}
+
+	var problems []ReferenceProblem
+	for _, o := range set {
+		if o.Object.Disabled(RuleReference) {
+			continue
+		}
+		for _, ref := range o.Object.References() {
+			if defined[key{ref.Kind, o.Object.Namespace(), ref.Name}] {
+				continue
+			}
+			msg := fmt.Sprintf("%s refers to the %s %q in %s, which is not defined", o.Object.Name(), ref.Kind, ref.Name, ref.Field)
+			names := kinds[o.Path][ref.Kind]
+			if len(names) > 0 {
+				sort.Strings(names)
+				msg += fmt.Sprintf(" (%ss of the file: %s)", ref.Kind, strings.Join(names, ", "))
+			}
+			problems = append(problems, ReferenceProblem{From: o, Message: msg, LikelyMistake: len(names) > 0})
+		}
+	}
+	return problems
+}
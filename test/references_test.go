@@ -0,0 +1,110 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	examples "k8s.io/website/test"
+)
+
+// Resolves the references between the objects of the examples of each
+// directory. References that are likely mistakes fail the test, the others
+// are logged, since the referenced objects may be created by the page with
+// kubectl.
+func TestExampleReferences(t *testing.T) {
+	ignore, err := examples.ReadIgnore(examples.IgnoreFile, "..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets := map[string][]examples.Entry{}
+	for _, root := range exampleRoots {
+		err := examples.Walk(root, ignore, func(f *examples.File) {
+			dir := filepath.Dir(f.Path)
+			sets[dir] = append(sets[dir], f.Entries()...)
+		})
+		if err != nil {
+			t.Errorf("Expected no error, Got %v on Path %v", err, root)
+		}
+	}
+
+	var dirs []string
+	for dir := range sets {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		for _, p := range examples.CheckReferences(sets[dir]) {
+			if p.LikelyMistake {
+				t.Errorf("%s: %s", p.From, p.Message)
+			} else {
+				t.Logf("%s: %s", p.From, p.Message)
+			}
+		}
+	}
+}
+
+func TestCheckReferences(t *testing.T) {
+	docs := []string{
+		`{"kind": "Service", "metadata": {"name": "mysql"}, "spec": {"selector": {"app": "mysql"}}}`,
+		`{"kind": "Service", "metadata": {"name": "web"}, "spec": {"selector": {"app": "web"}}}`,
+		`{"kind": "PersistentVolumeClaim", "metadata": {"name": "mysql-pv-claim"}}`,
+		`{"kind": "Deployment", "metadata": {"name": "mysql"}, "spec": {"template": {
+			"metadata": {"labels": {"app": "mysql"}},
+			"spec": {
+				"containers": [{"name": "mysql", "env": [
+					{"name": "PASSWORD", "valueFrom": {"secretKeyRef": {"name": "mysql-pass", "key": "password"}}},
+					{"name": "MODE", "valueFrom": {"configMapKeyRef": {"name": "mode", "key": "mode", "optional": true}}}
+				]}],
+				"volumes": [
+					{"name": "data", "persistentVolumeClaim": {"claimName": "mysql-pvc"}},
+					{"name": "config", "configMap": {"name": "mysql-config"}}
+				]
+			}}}}`,
+		`{"kind": "ConfigMap", "metadata": {"name": "mysql-config"}}`,
+		`{"kind": "HorizontalPodAutoscaler", "metadata": {"name": "mysql"}, "spec": {"scaleTargetRef": {"kind": "Deployment", "name": "mysql"}}}`,
+		`{"kind": "HorizontalPodAutoscaler", "metadata": {"name": "web"}, "spec": {"scaleTargetRef": {"kind": "Deployment", "name": "web"}}}`,
+		`{"kind": "Pod", "metadata": {"name": "debug", "annotations": {"website.k8s.io/lint-disable": "reference"}}, "spec": {
+			"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "debug-pvc"}}]}}`,
+	}
+	var set []examples.Entry
+	for i, doc := range docs {
+		var obj examples.Object
+		if err := json.Unmarshal([]byte(doc), &obj); err != nil {
+			t.Fatal(err)
+		}
+		set = append(set, examples.Entry{Path: "mysql.yaml", Doc: i, Object: obj})
+	}
+
+	var got []string
+	for _, p := range examples.CheckReferences(set) {
+		got = append(got, fmt.Sprintf("%d %v %s", p.From.Doc, p.LikelyMistake, p.Message))
+	}
+	want := []string{
+		`3 true Deployment "mysql" refers to the PersistentVolumeClaim "mysql-pvc" in volumes[0].persistentVolumeClaim.claimName, which is not defined (PersistentVolumeClaims of the file: mysql-pv-claim)`,
+		`3 false Deployment "mysql" refers to the Secret "mysql-pass" in container "mysql" env PASSWORD, which is not defined`,
+		`6 true HorizontalPodAutoscaler "web" refers to the Deployment "web" in spec.scaleTargetRef, which is not defined (Deployments of the file: mysql)`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
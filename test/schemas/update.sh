@@ -0,0 +1,17 @@
+#!/bin/bash
+# Downloads the OpenAPI schema of every release listed in ./versions that is
+# not stored yet, to <version>.json.
+set -o errexit
+set -o nounset
+set -o pipefail
+
+cd "$(dirname "$0")"
+grep -v '^#' versions | while read -r version tag; do
+  if [ -z "${version}" ] || [ -f "${version}.json" ]; then
+    continue
+  fi
+  echo "Downloading the OpenAPI schema of ${version} (${tag})"
+  curl -fsSL -o "${version}.json.tmp" \
+    "https://raw.githubusercontent.com/kubernetes/kubernetes/${tag}/api/openapi-spec/swagger.json"
+  mv "${version}.json.tmp" "${version}.json"
+done
@@ -0,0 +1,315 @@
+/*
+Copyright 2018 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package examples_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	examples "k8s.io/website/test"
+)
+
+// schemaDir holds the OpenAPI schema of each release of schemaVersionsFile,
+// as <version>.json.
+const schemaDir = "schemas"
+
+// schemaVersionsFile lists the releases to validate the examples against,
+// newest first, as "<version> <git tag>" lines.
+var schemaVersionsFile = filepath.Join(schemaDir, "versions")
+
+// openAPISchema is the part of the Swagger 2.0 spec of a release that
+// examples are validated against.
+type openAPISchema struct {
+	Definitions map[string]*schemaDefinition `json:"definitions"`
+	// kinds maps "<apiVersion> <kind>" to the definition of a kind.
+	kinds map[string]*schemaDefinition
+}
+
+// schemaDefinition is a definition, or the schema of a property.
+type schemaDefinition struct {
+	Type                 string                       `json:"type"`
+	Format               string                       `json:"format"`
+	Ref                  string                       `json:"$ref"`
+	Properties           map[string]*schemaDefinition `json:"properties"`
+	AdditionalProperties *schemaDefinition            `json:"additionalProperties"`
+	Items                *schemaDefinition            `json:"items"`
+	Required             []string                     `json:"required"`
+	GroupVersionKinds    []struct {
+		Group   string `json:"group"`
+		Version string `json:"version"`
+		Kind    string `json:"kind"`
+	} `json:"x-kubernetes-group-version-kind"`
+}
+
+// Definitions that are serialized as a string or a number, while the spec
+// only allows one of them
+var stringOrNumberDefinitions = map[string]bool{
+	"io.k8s.apimachinery.pkg.api.resource.Quantity":   true,
+	"io.k8s.apimachinery.pkg.util.intstr.IntOrString": true,
+}
+
+// readOpenAPISchema reads the spec at file.
+func readOpenAPISchema(file string) (*openAPISchema, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseOpenAPISchema(data)
+}
+
+func parseOpenAPISchema(data []byte) (*openAPISchema, error) {
+	s := &openAPISchema{kinds: map[string]*schemaDefinition{}}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	for _, def := range s.Definitions {
+		for _, gvk := range def.GroupVersionKinds {
+			apiVersion := gvk.Version
+			if gvk.Group != "" {
+				apiVersion = gvk.Group + "/" + gvk.Version
+			}
+			s.kinds[apiVersion+" "+gvk.Kind] = def
+		}
+	}
+	return s, nil
+}
+
+// validate checks the decoded JSON of a document against the definition of
+// its apiVersion and kind, and returns the problems found.
+func (s *openAPISchema) validate(doc interface{}) []string {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return []string{"the document is not an object"}
+	}
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	def, ok := s.kinds[apiVersion+" "+kind]
+	if !ok {
+		return []string{fmt.Sprintf("%s %s is not served", apiVersion, kind)}
+	}
+	var problems []string
+	s.validateValue(obj, def, "", &problems)
+	return problems
+}
+
+func (s *openAPISchema) validateValue(value interface{}, def *schemaDefinition, path string, problems *[]string) {
+	if value == nil {
+		return
+	}
+	if def.Ref != "" {
+		name := strings.TrimPrefix(def.Ref, "#/definitions/")
+		if stringOrNumberDefinitions[name] {
+			switch value.(type) {
+			case string, float64:
+			default:
+				*problems = append(*problems, fmt.Sprintf("%s: expected a string or a number, got %T", path, value))
+			}
+			return
+		}
+		ref, ok := s.Definitions[name]
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: unknown definition %q", path, name))
+			return
+		}
+		def = ref
+	}
+
+	typ := def.Type
+	if typ == "" && def.Properties != nil {
+		typ = "object"
+	}
+	switch typ {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected an object, got %T", path, value))
+			return
+		}
+		for _, name := range def.Required {
+			if _, ok := obj[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required field %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			if prop, ok := def.Properties[name]; ok {
+				s.validateValue(obj[name], prop, fieldPath, problems)
+			} else if def.AdditionalProperties != nil {
+				s.validateValue(obj[name], def.AdditionalProperties, fieldPath, problems)
+			} else if def.Properties != nil {
+				*problems = append(*problems, fmt.Sprintf("%s: unknown field", fieldPath))
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected an array, got %T", path, value))
+			return
+		}
+		if def.Items == nil {
+			return
+		}
+		for i, item := range items {
+			s.validateValue(item, def.Items, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected a string, got %T", path, value))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			*problems = append(*problems, fmt.Sprintf("%s: expected an integer, got %v", path, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected a number, got %T", path, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			*problems = append(*problems, fmt.Sprintf("%s: expected a boolean, got %T", path, value))
+		}
+	}
+}
+
+// Validates every example against the OpenAPI schema of each release of
+// schemaVersionsFile, and reports which releases accept each document. The
+// release of these docs, the first one, must accept all of them.
+func TestExampleVersionMatrix(t *testing.T) {
+	versions, err := examples.ReadSchemaVersions(schemaVersionsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schemas := map[string]*openAPISchema{}
+	var missing []string
+	for _, version := range versions {
+		file := filepath.Join(schemaDir, version+".json")
+		schema, err := readOpenAPISchema(file)
+		if os.IsNotExist(err) {
+			missing = append(missing, version)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		schemas[version] = schema
+	}
+	if len(missing) > 0 {
+		t.Fatalf("No OpenAPI schema in %s for %s, run %s/update.sh to download them", schemaDir, strings.Join(missing, ", "), schemaDir)
+	}
+	ignore, err := examples.ReadIgnore(examples.IgnoreFile, "..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var matrix []string
+	for _, root := range exampleRoots {
+		err := examples.Walk(root, ignore, func(f *examples.File) {
+			path := f.Path
+			for i, data := range f.JSON() {
+				var doc interface{}
+				if err := json.Unmarshal(data, &doc); err != nil {
+					t.Errorf("%s: document %d is not valid JSON: %v", path, i+1, err)
+					continue
+				}
+				row := fmt.Sprintf("%s (document %d):", path, i+1)
+				for _, version := range versions {
+					problems := schemas[version].validate(doc)
+					if len(problems) == 0 {
+						row += " " + version
+						continue
+					}
+					if version == versions[0] {
+						t.Errorf("%s: document %d is not accepted by %s, the release of these docs:\n\t%s", path, i+1, version, strings.Join(problems, "\n\t"))
+					}
+				}
+				matrix = append(matrix, row)
+			}
+		})
+		if err != nil {
+			t.Errorf("Expected no error, Got %v on Path %v", err, root)
+		}
+	}
+	t.Logf("Releases that accept each example, of %s:\n%s", strings.Join(versions, ", "), strings.Join(matrix, "\n"))
+}
+
+func TestOpenAPIValidation(t *testing.T) {
+	schema, err := parseOpenAPISchema([]byte(`{"definitions": {
+  "io.k8s.api.core.v1.Pod": {
+    "properties": {
+      "apiVersion": {"type": "string"}, "kind": {"type": "string"},
+      "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+      "spec": {"$ref": "#/definitions/io.k8s.api.core.v1.PodSpec"}
+    },
+    "x-kubernetes-group-version-kind": [{"group": "", "version": "v1", "kind": "Pod"}]
+  },
+  "io.k8s.api.core.v1.PodSpec": {
+    "required": ["containers"],
+    "properties": {
+      "containers": {"type": "array", "items": {"$ref": "#/definitions/io.k8s.api.core.v1.Container"}},
+      "hostNetwork": {"type": "boolean"}
+    }
+  },
+  "io.k8s.api.core.v1.Container": {
+    "required": ["name"],
+    "properties": {
+      "name": {"type": "string"},
+      "ports": {"type": "array", "items": {"properties": {"containerPort": {"type": "integer", "format": "int32"}}}},
+      "resources": {"properties": {"limits": {"type": "object", "additionalProperties": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.api.resource.Quantity"}}}}
+    }
+  },
+  "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+    "properties": {"name": {"type": "string"}, "labels": {"type": "object", "additionalProperties": {"type": "string"}}}
+  },
+  "io.k8s.apimachinery.pkg.api.resource.Quantity": {"type": "string"}
+}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "a", "labels": {"app": "a"}},
+		  "spec": {"containers": [{"name": "a", "ports": [{"containerPort": 80}], "resources": {"limits": {"cpu": 1, "memory": "1Gi"}}}]}}`: "",
+		`{"apiVersion": "apps/v1", "kind": "Pod"}`:                                                                        "apps/v1 Pod is not served",
+		`{"apiVersion": "v1", "kind": "Pod", "spec": {}}`:                                                                 `spec: missing required field "containers"`,
+		`{"apiVersion": "v1", "kind": "Pod", "spec": {"containers": [{"name": "a", "image": "nginx"}]}}`:                  "spec.containers[0].image: unknown field",
+		`{"apiVersion": "v1", "kind": "Pod", "spec": {"containers": [], "hostNetwork": "yes"}}`:                           "spec.hostNetwork: expected a boolean, got string",
+		`{"apiVersion": "v1", "kind": "Pod", "spec": {"containers": [{"name": "a", "ports": [{"containerPort": 1.5}]}]}}`: "spec.containers[0].ports[0].containerPort: expected an integer, got 1.5",
+	}
+	for doc, want := range cases {
+		var value interface{}
+		if err := json.Unmarshal([]byte(doc), &value); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(schema.validate(value), "; "); got != want {
+			t.Errorf("%s: got %q, want %q", doc, got, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// assetExtensions are the file types that are copied into the assets
+// directory instead of being linked on GitHub.
+var assetExtensions = map[string]bool{
+	".png":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".gif":  true,
+	".svg":  true,
+	".pdf":  true,
+}
+
+// To catch the src of <img> tags
+var imgTagRegex = regexp.MustCompile(`(<img\s[^>]*?src=["'])([^"']+)(["'])`)
+
+// assetSparsePaths are checked out in sparse clones when assets are imported,
+// because the referenced files are not known before the clone.
+func assetSparsePaths() []string {
+	var paths []string
+	for ext := range assetExtensions {
+		paths = append(paths, "**/*"+ext)
+	}
+	return paths
+}
+
+// assetCopier copies the assets referenced by the imported files of a repo
+// into the site, named by the hash of their content.
+type assetCopier struct {
+	im       *Importer
+	cfg      Config
+	repo     Repo
+	cloneDir string
+	// copied maps a site path to true once written, to skip duplicates.
+	copied map[string]bool
+	// warnings are the references to assets that could not be copied.
+	warnings []string
+}
+
+// rewrite copies the local assets referenced by the file at src and returns
+// content with the references pointing at the copies.
+func (a *assetCopier) rewrite(content []byte, src string) ([]byte, error) {
+	var firstErr error
+	replace := func(url string) string {
+		sitePath, err := a.copy(url, src)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return url
+		}
+		if sitePath == "" {
+			return url
+		}
+		return sitePath
+	}
+
+	content = linkRegex.ReplaceAllFunc(content, func(b []byte) []byte {
+		match := linkRegex.FindSubmatch(b)
+		return []byte(fmt.Sprintf("%s(%s)", match[1], replace(string(match[2]))))
+	})
+	content = imgTagRegex.ReplaceAllFunc(content, func(b []byte) []byte {
+		match := imgTagRegex.FindSubmatch(b)
+		return []byte(fmt.Sprintf("%s%s%s", match[1], replace(string(match[2])), match[3]))
+	})
+	return content, firstErr
+}
+
+// copy copies the asset that url refers to from the file at src, and
+// returns its path on the site. It returns "" if url is not a local asset,
+// or if the asset is missing or outside of the repo, which is a warning.
+func (a *assetCopier) copy(url string, src string) (string, error) {
+	if absURLRegex.MatchString(url) || mailRegex.MatchString(url) || strings.HasPrefix(url, "#") {
+		return "", nil
+	}
+	// Drop the query and fragment, e.g. diagram.png?raw=true
+	file := url
+	if i := strings.IndexAny(file, "?#"); i >= 0 {
+		file = file[:i]
+	}
+	ext := strings.ToLower(path.Ext(file))
+	if !assetExtensions[ext] {
+		return "", nil
+	}
+
+	var repoPath string
+	if strings.HasPrefix(file, "/") { // link at root of repo
+		repoPath = path.Clean(file[1:])
+	} else { // link relative to current page
+		repoPath = path.Join(path.Dir(src), file)
+	}
+	if repoPath == ".." || strings.HasPrefix(repoPath, "../") {
+		a.warnf("asset %q in %s is outside of repo %q, leaving the link alone", url, src, a.repo.Name)
+		return "", nil
+	}
+
+	data, err := a.im.FS.ReadFile(filepath.Join(a.cloneDir, filepath.FromSlash(repoPath)))
+	if os.IsNotExist(err) {
+		a.warnf("asset %q in %s is missing from repo %q, leaving the link alone", url, src, a.repo.Name)
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("asset %q: %v", url, err)
+	}
+	sum := sha256.Sum256(data)
+	sitePath := path.Join(a.cfg.AssetsDir, a.repo.Name, hex.EncodeToString(sum[:8])+ext)
+	if !a.copied[sitePath] {
+		abs := filepath.Join(a.cfg.SiteRoot, filepath.FromSlash(sitePath))
+		if err := a.im.FS.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return "", err
+		}
+		if err := a.im.FS.WriteFile(abs, data, 0644); err != nil {
+			return "", err
+		}
+		a.copied[sitePath] = true
+	}
+	return "/" + sitePath, nil
+}
+
+func (a *assetCopier) warnf(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	a.warnings = append(a.warnings, warning)
+	a.im.logf("Warning: %s\n", warning)
+}
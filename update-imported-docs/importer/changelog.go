@@ -0,0 +1,265 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/website/update-imported-docs/marker"
+)
+
+// ModeChangelog splits a CHANGELOG into one page per release, see
+// File.Mode.
+const ModeChangelog = "changelog"
+
+// To catch release headings, e.g. "# v1.10.0" or "# v1.10.0-beta.1"
+var releaseHeadingRegex = regexp.MustCompile(`^# (v\d+\.\d+\.\d+\S*)\s*$`)
+
+// To catch the generated table of contents of a CHANGELOG
+var mungeTOCRegex = regexp.MustCompile(`(?s)<!-- BEGIN MUNGE: GENERATED_TOC -->.*?<!-- END MUNGE: GENERATED_TOC -->\n?`)
+
+// To catch top-level list items
+var listItemRegex = regexp.MustCompile(`^[*-][ \t]+(.*)$`)
+
+// notableSections are the sections whose items are kept in the front matter
+// of a release page.
+var notableSections = map[string]bool{
+	"Action Required":  true,
+	"Before Upgrading": true,
+	"Known Issues":     true,
+	"Deprecations":     true,
+}
+
+// ChangelogSection is a heading of a release page.
+type ChangelogSection struct {
+	Title string `json:"title"`
+	// ID is the anchor of the heading on the release page.
+	ID    string `json:"id"`
+	Level int    `json:"level"`
+	// Items are the list items of notable sections, e.g. "Action Required".
+	Items []string `json:"items,omitempty"`
+}
+
+// changelogRelease is one release of a CHANGELOG.
+type changelogRelease struct {
+	Version  string
+	Body     []byte
+	Sections []ChangelogSection
+}
+
+// splitChangelog splits content at its release headings. Anything before the
+// first release heading is dropped.
+func splitChangelog(content []byte) []changelogRelease {
+	content = mungeTOCRegex.ReplaceAll(content, nil)
+
+	var releases []changelogRelease
+	var body bytes.Buffer
+	flush := func() {
+		if len(releases) > 0 {
+			r := &releases[len(releases)-1]
+			r.Body = append([]byte{}, bytes.TrimSpace(body.Bytes())...)
+			r.Body = append(r.Body, '\n')
+			r.Sections = changelogSections(r.Body)
+		}
+		body.Reset()
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 1024*1024)
+	fence := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if fence == "" {
+			if m := releaseHeadingRegex.FindStringSubmatch(line); m != nil {
+				flush()
+				releases = append(releases, changelogRelease{Version: m[1]})
+				continue
+			}
+		}
+		fence = updateFence(fence, line)
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	flush()
+	return releases
+}
+
+// changelogSections returns the level 2 and 3 headings of a release, with the
+// items of the notable ones.
+func changelogSections(body []byte) []ChangelogSection {
+	var sections []ChangelogSection
+	var current *ChangelogSection
+	walkMarkdown(body, func(line string, h *heading) {
+		if h != nil {
+			current = nil
+			if h.Level == 2 || h.Level == 3 {
+				sections = append(sections, ChangelogSection{Title: h.Title, ID: h.ID, Level: h.Level})
+				if notableSections[h.Title] {
+					current = &sections[len(sections)-1]
+				}
+			}
+			return
+		}
+		if current == nil {
+			return
+		}
+		if m := listItemRegex.FindStringSubmatch(line); m != nil {
+			current.Items = append(current.Items, strings.TrimSpace(m[1]))
+		} else if n := len(current.Items); n > 0 && strings.TrimSpace(line) != "" && (line[0] == ' ' || line[0] == '\t') {
+			// continuation of the previous item
+			current.Items[n-1] += " " + strings.TrimSpace(line)
+		}
+	})
+	return sections
+}
+
+// changelogAnchors maps the heading IDs of a whole CHANGELOG to the link of
+// the same heading once it is split into pages below dir. Headings of
+// different releases often share a title, so IDs change with the split, e.g.
+// "#known-issues-1" may become "/dir/v1.9.0/#known-issues".
+func changelogAnchors(content []byte, dir string) map[string]string {
+	content = mungeTOCRegex.ReplaceAll(content, nil)
+	anchors := map[string]string{}
+	url := ""
+	var counts map[string]int
+	walkMarkdown(content, func(line string, h *heading) {
+		if h == nil {
+			return
+		}
+		if m := releaseHeadingRegex.FindStringSubmatch(line); m != nil {
+			url = "/" + path.Join(dir, m[1]) + "/"
+			counts = map[string]int{}
+			anchors[h.ID] = url
+			return
+		}
+		if url == "" {
+			return
+		}
+		id := h.ID
+		if explicitIDRegex.FindString(line) == "" {
+			id = headingID(h.Title)
+			if n := counts[id]; n > 0 {
+				counts[id]++
+				id = fmt.Sprintf("%s-%d", id, n)
+			} else {
+				counts[id]++
+			}
+		}
+		anchors[h.ID] = url + "#" + id
+	})
+	return anchors
+}
+
+// rewriteAnchors points the in-page anchors of the release page at url to
+// where the headings are after the split.
+func rewriteAnchors(body []byte, url string, anchors map[string]string) []byte {
+	return linkRegex.ReplaceAllFunc(body, func(b []byte) []byte {
+		match := linkRegex.FindSubmatch(b)
+		target := string(match[2])
+		if !strings.HasPrefix(target, "#") {
+			return b
+		}
+		link, ok := anchors[target[1:]]
+		if !ok {
+			return b
+		}
+		if strings.HasPrefix(link, url+"#") { // heading on this page
+			link = link[len(url):]
+		}
+		return []byte(fmt.Sprintf("%s(%s)", match[1], link))
+	})
+}
+
+// writeChangelog writes one page per release of content below the directory
+// named after dst without its extension, and an index of them at dst. It
+// returns the written paths, relative to the site root.
+func (im *Importer) writeChangelog(siteRoot string, dst string, titleBlock []byte, content []byte) ([]string, error) {
+	releases := splitChangelog(content)
+	if len(releases) == 0 {
+		return nil, fmt.Errorf("no release headings like \"# v1.10.0\" found")
+	}
+	dir := strings.TrimSuffix(dst, path.Ext(dst))
+	anchors := changelogAnchors(content, dir)
+
+	type indexEntry struct {
+		Version string `json:"version"`
+		Path    string `json:"path"`
+	}
+	var written []string
+	var entries []indexEntry
+	var index bytes.Buffer
+	for _, r := range releases {
+		pagePath := path.Join(dir, r.Version+".md")
+		url := "/" + path.Join(dir, r.Version) + "/"
+
+		frontMatter, err := yaml.Marshal(map[string]interface{}{
+			"release":  r.Version,
+			"sections": r.Sections,
+		})
+		if err != nil {
+			return written, err
+		}
+		var page bytes.Buffer
+		fmt.Fprintf(&page, "---\ntitle: %s Release Notes\n%s---\n\n", r.Version, frontMatter)
+		page.Write(rewriteAnchors(r.Body, url, anchors))
+		if err := im.writeSiteFile(siteRoot, pagePath, page.Bytes()); err != nil {
+			return written, err
+		}
+		written = append(written, pagePath)
+		entries = append(entries, indexEntry{Version: r.Version, Path: url})
+
+		fmt.Fprintf(&index, "- [%s](%s)\n", r.Version, url)
+		for _, s := range r.Sections {
+			if notableSections[s.Title] {
+				fmt.Fprintf(&index, "  - [%s](%s#%s)\n", s.Title, url, s.ID)
+			}
+		}
+	}
+
+	// keep the front matter of the page, and add the list of releases
+	fields := map[string]interface{}{}
+	if titleBlock != nil {
+		if err := yaml.Unmarshal(titleBlock[len("---\n"):len(titleBlock)-len("---\n")], &fields); err != nil {
+			return written, err
+		}
+	}
+	title, ok := fields["title"]
+	if !ok {
+		title = "Release Notes"
+	}
+	delete(fields, "title")
+	fields["releases"] = entries
+	titleLine, err := yaml.Marshal(map[string]interface{}{"title": title})
+	if err != nil {
+		return written, err
+	}
+	frontMatter, err := yaml.Marshal(fields)
+	if err != nil {
+		return written, err
+	}
+	var page bytes.Buffer
+	page.WriteString("---\n")
+	page.Write(titleLine)
+	page.Write(frontMatter)
+	page.WriteString("---\n\n")
+	page.Write(index.Bytes())
+	if err := im.writeSiteFile(siteRoot, dst, page.Bytes()); err != nil {
+		return written, err
+	}
+	return append(written, dst), nil
+}
+
+// writeSiteFile writes the file at the site path p, with a generated-file
+// marker.
+func (im *Importer) writeSiteFile(siteRoot string, p string, data []byte) error {
+	abs := filepath.Join(siteRoot, filepath.FromSlash(p))
+	if err := im.FS.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return err
+	}
+	return im.FS.WriteFile(abs, marker.Stamp(p, data), 0644)
+}
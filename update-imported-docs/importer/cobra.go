@@ -0,0 +1,317 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+)
+
+// GeneratorCobra generates reference pages from the cobra command trees of a
+// repo, see Repo.Generator.
+const GeneratorCobra = "cobra"
+
+// CobraCommand is a cobra command tree to generate reference pages for.
+type CobraCommand struct {
+	// Imports are the packages Command needs, optionally with a name,
+	// e.g. "k8s.io/kubernetes/cmd/kube-apiserver/app".
+	Imports []string `json:"imports"`
+	// Command is a Go expression that returns the root *cobra.Command,
+	// e.g. "app.NewAPIServerCommand()". encoding/json, os and cobra are
+	// imported besides Imports.
+	Command string `json:"command"`
+	// Dst is the directory of the pages. Each command is written to a page
+	// named after its path, e.g. kubectl_config_view.md.
+	Dst string `json:"dst"`
+	// TOC is an optional data file to write the table of contents of the
+	// pages to, in the format of the _data/*.yml files. With releases, the
+	// version is appended to its name, e.g. _data/kubectl-v1.10.yml.
+	TOC string `json:"toc,omitempty"`
+}
+
+// cobraDoc is the description of a command that the generator program
+// prints.
+type cobraDoc struct {
+	Path           string     `json:"path"`
+	Short          string     `json:"short"`
+	Long           string     `json:"long"`
+	UseLine        string     `json:"useLine"`
+	Runnable       bool       `json:"runnable"`
+	Example        string     `json:"example"`
+	Flags          string     `json:"flags"`
+	InheritedFlags string     `json:"inheritedFlags"`
+	Commands       []cobraDoc `json:"commands"`
+	parent         *cobraDoc
+	page, sitePath string
+	slug           string
+}
+
+// cobraProgram walks a cobra command tree and prints it as JSON. It is built
+// against the cloned module, so it uses the cobra version of the repo.
+var cobraProgram = template.Must(template.New("main.go").Parse(`// Code generated by update-imported-docs. DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+{{range .Imports}}	{{.}}
+{{end}})
+
+type doc struct {
+	Path           string ` + "`json:\"path\"`" + `
+	Short          string ` + "`json:\"short\"`" + `
+	Long           string ` + "`json:\"long\"`" + `
+	UseLine        string ` + "`json:\"useLine\"`" + `
+	Runnable       bool   ` + "`json:\"runnable\"`" + `
+	Example        string ` + "`json:\"example\"`" + `
+	Flags          string ` + "`json:\"flags\"`" + `
+	InheritedFlags string ` + "`json:\"inheritedFlags\"`" + `
+	Commands       []doc  ` + "`json:\"commands\"`" + `
+}
+
+func walk(cmd *cobra.Command) doc {
+	// as cobra/doc does, so that the pages list --help
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultHelpFlag()
+	d := doc{
+		Path:           cmd.CommandPath(),
+		Short:          cmd.Short,
+		Long:           cmd.Long,
+		UseLine:        cmd.UseLine(),
+		Runnable:       cmd.Runnable(),
+		Example:        cmd.Example,
+		Flags:          cmd.NonInheritedFlags().FlagUsages(),
+		InheritedFlags: cmd.InheritedFlags().FlagUsages(),
+	}
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		d.Commands = append(d.Commands, walk(c))
+	}
+	return d
+}
+
+func main() {
+	var cmd *cobra.Command = {{.Command}}
+	cmd.DisableAutoGenTag = true
+	if err := json.NewEncoder(os.Stdout).Encode(walk(cmd)); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+`))
+
+// cobraProgramNames are the names that cobraProgram declares, which imports
+// cannot reuse.
+var cobraProgramNames = map[string]bool{"json": true, "os": true, "cobra": true, "doc": true, "walk": true}
+
+// checkImport returns an error if the import spec redeclares a name of
+// cobraProgram, e.g. "os" or "github.com/spf13/cobra".
+func checkImport(spec string) error {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return fmt.Errorf("invalid import %q", spec)
+	}
+	name := path.Base(fields[len(fields)-1])
+	if len(fields) == 2 {
+		name = fields[0]
+	}
+	if cobraProgramNames[name] {
+		return fmt.Errorf("import %q redeclares %s of the generator program, which always imports encoding/json, os and github.com/spf13/cobra", spec, name)
+	}
+	return nil
+}
+
+// formatImport quotes the path of an import spec, e.g. `app k8s.io/x/app`
+// becomes `app "k8s.io/x/app"`.
+func formatImport(spec string) string {
+	fields := strings.Fields(spec)
+	if len(fields) == 2 {
+		return fmt.Sprintf("%s %q", fields[0], fields[1])
+	}
+	return fmt.Sprintf("%q", spec)
+}
+
+// generateCobra builds and runs the generator program for every command of
+// repo in the clone, and writes their pages. It returns the written paths,
+// relative to the site root.
+func (im *Importer) generateCobra(ctx context.Context, cfg Config, cloneDir string, repo Repo, versionDir string) ([]string, error) {
+	var written []string
+	for _, c := range repo.Commands {
+		var imports []string
+		for _, spec := range c.Imports {
+			imports = append(imports, formatImport(spec))
+		}
+		var program bytes.Buffer
+		err := cobraProgram.Execute(&program, struct {
+			Imports []string
+			Command string
+		}{imports, c.Command})
+		if err != nil {
+			return written, err
+		}
+
+		im.logf("Generating docs for %q of repo %q...\n", c.Command, repo.Name)
+		out, err := im.runCobraProgram(ctx, cloneDir, program.Bytes())
+		if err != nil {
+			return written, fmt.Errorf("running the generator for %q: %v", c.Command, err)
+		}
+		var root cobraDoc
+		if err := json.Unmarshal(out, &root); err != nil {
+			return written, fmt.Errorf("reading the output of the generator for %q: %v", c.Command, err)
+		}
+
+		dst, toc := path.Clean(c.Dst), c.TOC
+		if versionDir != "" {
+			if dst, err = versionedPath(dst, versionDir); err != nil {
+				return written, err
+			}
+			toc = versionedTOC(toc, versionDir)
+		}
+		pages, err := im.writeCobraPages(cfg.SiteRoot, dst, toc, &root)
+		written = append(written, pages...)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// runCobraProgram runs a generator program with `go run` and returns its
+// output. The program is written to a temporary directory of the clone, since
+// it must be inside the module to import its packages, and removed after.
+func (im *Importer) runCobraProgram(ctx context.Context, cloneDir string, program []byte) ([]byte, error) {
+	progDir, err := im.FS.TempDir(cloneDir, "update-imported-docs-cobra")
+	if err != nil {
+		return nil, err
+	}
+	defer im.FS.RemoveAll(progDir)
+	if err := im.FS.WriteFile(filepath.Join(progDir, "main.go"), program, 0644); err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(cloneDir, progDir)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := im.Exec.Run(ctx, cloneDir, &out, "go", "run", "./"+filepath.ToSlash(rel)); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// versionedTOC returns the name of the table of contents toc for versionDir,
+// e.g. _data/kubectl-v1.10.yml, or toc if versionDir is empty.
+func versionedTOC(toc string, versionDir string) string {
+	if toc == "" || versionDir == "" {
+		return toc
+	}
+	ext := path.Ext(toc)
+	return strings.TrimSuffix(toc, ext) + "-" + path.Base(versionDir) + ext
+}
+
+// writeCobraPages writes a page for root and each of its subcommands into
+// dir, and the table of contents to toc if it is set.
+func (im *Importer) writeCobraPages(siteRoot string, dir string, toc string, root *cobraDoc) ([]string, error) {
+	// name the pages first, so that they can link to each other
+	var all []*cobraDoc
+	var name func(d *cobraDoc, parent *cobraDoc)
+	name = func(d *cobraDoc, parent *cobraDoc) {
+		d.parent = parent
+		d.slug = strings.Replace(d.Path, " ", "_", -1)
+		d.page = path.Join(dir, d.slug+".md")
+		d.sitePath = "/" + path.Join(dir, d.slug) + "/"
+		all = append(all, d)
+		for i := range d.Commands {
+			name(&d.Commands[i], d)
+		}
+	}
+	name(root, nil)
+
+	var written []string
+	for _, d := range all {
+		if err := im.writeSiteFile(siteRoot, d.page, cobraPage(d)); err != nil {
+			return written, err
+		}
+		written = append(written, d.page)
+	}
+
+	if toc != "" {
+		data, err := yaml.Marshal(map[string]interface{}{
+			"bigheader": root.Path + " Reference",
+			"toc":       []interface{}{cobraTOC(root)},
+		})
+		if err != nil {
+			return written, err
+		}
+		if err := im.writeSiteFile(siteRoot, toc, data); err != nil {
+			return written, err
+		}
+		written = append(written, toc)
+	}
+	return written, nil
+}
+
+// cobraTOC returns the table of contents entry of d: its page, or a section
+// with its page and those of its subcommands.
+func cobraTOC(d *cobraDoc) interface{} {
+	if len(d.Commands) == 0 {
+		return d.page
+	}
+	section := []interface{}{d.page}
+	for i := range d.Commands {
+		section = append(section, cobraTOC(&d.Commands[i]))
+	}
+	return map[string]interface{}{"title": d.Path, "section": section}
+}
+
+// cobraPage renders d in the format of the pages of spf13/cobra/doc, with the
+// front matter of the site.
+func cobraPage(d *cobraDoc) []byte {
+	var b bytes.Buffer
+	title, _ := yaml.Marshal(map[string]string{"title": d.Path})
+	b.WriteString("---\n")
+	b.Write(title)
+	b.WriteString("notitle: true\n---\n")
+	fmt.Fprintf(&b, "## %s\n\n%s\n\n", d.Path, d.Short)
+
+	b.WriteString("### Synopsis\n\n")
+	long := d.Long
+	if long == "" {
+		long = d.Short
+	}
+	fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(long))
+	if d.Runnable {
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", d.UseLine)
+	}
+	if d.Example != "" {
+		fmt.Fprintf(&b, "### Examples\n\n```\n%s\n```\n\n", strings.TrimRight(d.Example, "\n"))
+	}
+	if strings.TrimSpace(d.Flags) != "" {
+		fmt.Fprintf(&b, "### Options\n\n```\n%s```\n\n", d.Flags)
+	}
+	if strings.TrimSpace(d.InheritedFlags) != "" {
+		fmt.Fprintf(&b, "### Options inherited from parent commands\n\n```\n%s```\n\n", d.InheritedFlags)
+	}
+
+	if d.parent != nil || len(d.Commands) > 0 {
+		b.WriteString("### SEE ALSO\n\n")
+		if p := d.parent; p != nil {
+			fmt.Fprintf(&b, "* [%s](%s)\t - %s\n", p.Path, p.sitePath, p.Short)
+		}
+		for _, c := range d.Commands {
+			fmt.Fprintf(&b, "* [%s](%s)\t - %s\n", c.Path, c.sitePath, c.Short)
+		}
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cobraRunner prints the command tree for `go run`, and keeps the program,
+// which is removed after it runs.
+type cobraRunner struct {
+	dir     string
+	args    []string
+	program []byte
+}
+
+func (r *cobraRunner) Run(ctx context.Context, dir string, stdout io.Writer, name string, args ...string) error {
+	r.dir, r.args = dir, append([]string{name}, args...)
+	program, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(args[len(args)-1]), "main.go"))
+	if err != nil {
+		return err
+	}
+	r.program = program
+	_, err = io.WriteString(stdout, `{
+  "path": "kubectl", "short": "kubectl controls the Kubernetes cluster manager", "long": "kubectl controls the Kubernetes cluster manager.",
+  "useLine": "kubectl [flags]", "runnable": true, "flags": "  -h, --help   help for kubectl\n",
+  "commands": [{
+    "path": "kubectl config", "short": "Modify kubeconfig files", "useLine": "kubectl config SUBCOMMAND",
+    "inheritedFlags": "      --kubeconfig string   Path to the kubeconfig file\n",
+    "commands": [{
+      "path": "kubectl config view", "short": "Display merged kubeconfig settings", "useLine": "kubectl config view [flags]",
+      "runnable": true, "example": "  kubectl config view\n"
+    }]
+  }]
+}`)
+	return err
+}
+
+func TestRunGeneratesCobraPages(t *testing.T) {
+	root, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	site := filepath.Join(root, "site")
+
+	cfg, err := ParseConfig([]byte(`
+repos:
+- name: kubernetes
+  remote: https://github.com/kubernetes/kubernetes.git
+  branch: master
+  generator: cobra
+  commands:
+  - imports:
+    - k8s.io/kubernetes/pkg/kubectl/cmd
+    - cmdutil k8s.io/kubernetes/pkg/kubectl/cmd/util
+    - io/ioutil
+    command: cmd.NewKubectlCommand(cmdutil.NewFactory(nil), os.Stdin, ioutil.Discard, ioutil.Discard)
+    dst: docs/reference/generated/kubectl
+    toc: _data/kubectl.yml
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SiteRoot = site
+	cfg.WorkDir = filepath.Join(root, "work")
+
+	runner := &cobraRunner{}
+	im := &Importer{Git: &fakeGit{}, Exec: runner, FS: OSFS{}}
+	report, err := im.Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(runner.args) != 3 || runner.args[0] != "go" || runner.args[1] != "run" || !strings.HasPrefix(runner.args[2], "./update-imported-docs-cobra") {
+		t.Errorf("unexpected generator command %q", strings.Join(runner.args, " "))
+	} else if _, err := os.Stat(filepath.Join(runner.dir, runner.args[2])); !os.IsNotExist(err) {
+		t.Errorf("expected the generator program to be removed, got %v", err)
+	}
+	program := runner.program
+	for _, want := range []string{
+		"\t\"k8s.io/kubernetes/pkg/kubectl/cmd\"\n",
+		"\tcmdutil \"k8s.io/kubernetes/pkg/kubectl/cmd/util\"\n",
+		"\t\"io/ioutil\"\n",
+		"var cmd *cobra.Command = cmd.NewKubectlCommand(",
+	} {
+		if !strings.Contains(string(program), want) {
+			t.Errorf("generator program does not contain %q:\n%s", want, program)
+		}
+	}
+
+	wantFiles := []string{
+		"docs/reference/generated/kubectl/kubectl.md",
+		"docs/reference/generated/kubectl/kubectl_config.md",
+		"docs/reference/generated/kubectl/kubectl_config_view.md",
+		"_data/kubectl.yml",
+	}
+	if got := strings.Join(report.Repos[0].Files, " "); got != strings.Join(wantFiles, " ") {
+		t.Errorf("unexpected files %q", got)
+	}
+
+	got := readStamped(t, filepath.Join(site, "docs/reference/generated/kubectl/kubectl_config.md"))
+	want := "---\ntitle: kubectl config\nnotitle: true\n---\n" +
+		"## kubectl config\n\nModify kubeconfig files\n\n" +
+		"### Synopsis\n\nModify kubeconfig files\n\n" +
+		"### Options inherited from parent commands\n\n```\n      --kubeconfig string   Path to the kubeconfig file\n```\n\n" +
+		"### SEE ALSO\n\n" +
+		"* [kubectl](/docs/reference/generated/kubectl/kubectl/)\t - kubectl controls the Kubernetes cluster manager\n" +
+		"* [kubectl config view](/docs/reference/generated/kubectl/kubectl_config_view/)\t - Display merged kubeconfig settings\n"
+	if got != want {
+		t.Errorf("unexpected page:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = readStamped(t, filepath.Join(site, "_data/kubectl.yml"))
+	want = `bigheader: kubectl Reference
+toc:
+- section:
+  - docs/reference/generated/kubectl/kubectl.md
+  - section:
+    - docs/reference/generated/kubectl/kubectl_config.md
+    - docs/reference/generated/kubectl/kubectl_config_view.md
+    title: kubectl config
+  title: kubectl
+`
+	if got != want {
+		t.Errorf("unexpected TOC:\n%s\nwant:\n%s", got, want)
+	}
+}
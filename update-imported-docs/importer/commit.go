@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CommitOptions configures Commit.
+type CommitOptions struct {
+	// ConfigName names the branch and the commit, e.g. "reference.yml".
+	ConfigName string
+	// Date names the branch.
+	Date time.Time
+}
+
+// CommitResult describes the commit of an import run.
+type CommitResult struct {
+	// Branch is the created branch, or empty if nothing changed.
+	Branch string
+	// Files are the committed paths, relative to the site root.
+	Files   []string
+	Message string
+}
+
+// Commit creates a branch in the git repo of the site and commits the files
+// of report that changed, with a message listing the old and new commit of
+// every repo. The old commit is read from the message of the last commit
+// that imported the same repo and branch. Nothing is pushed.
+func (im *Importer) Commit(ctx context.Context, cfg Config, report Report, opts CommitOptions) (CommitResult, error) {
+	var result CommitResult
+	written := map[string]bool{}
+	for _, rr := range report.Repos {
+		for _, f := range append(append([]string{}, rr.Files...), rr.Assets...) {
+			written[f] = true
+		}
+	}
+	if cfg.VersionIndex != "" {
+		written[path.Clean(cfg.VersionIndex)] = true
+	}
+	var paths []string
+	for p := range written {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return result, nil
+	}
+
+	changed, err := im.changedFiles(ctx, cfg.SiteRoot, paths)
+	if err != nil {
+		return result, err
+	}
+	if len(changed) == 0 {
+		im.logf("No imported file changed, nothing to commit\n")
+		return result, nil
+	}
+	result.Files = changed
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "Update imported docs from %s\n\n", opts.ConfigName)
+	for _, rr := range report.Repos {
+		key := commitKey(rr)
+		fmt.Fprintf(&msg, "%s%s..%s\n", key, im.lastImport(ctx, cfg.SiteRoot, key), rr.Commit)
+	}
+	msg.WriteString("\nChanged files:\n")
+	for _, f := range changed {
+		fmt.Fprintf(&msg, "  %s\n", f)
+	}
+	result.Message = msg.String()
+
+	base := fmt.Sprintf("imported-docs/%s-%s", strings.TrimSuffix(opts.ConfigName, path.Ext(opts.ConfigName)), opts.Date.Format("2006-01-02"))
+	result.Branch = base
+	for n := 2; im.siteGit(ctx, cfg.SiteRoot, nil, "rev-parse", "--verify", "--quiet", "refs/heads/"+result.Branch) == nil; n++ {
+		result.Branch = fmt.Sprintf("%s-%d", base, n)
+	}
+	if err := im.siteGit(ctx, cfg.SiteRoot, nil, "checkout", "-q", "-b", result.Branch); err != nil {
+		return result, err
+	}
+	if err := im.siteGit(ctx, cfg.SiteRoot, nil, append([]string{"add", "--"}, changed...)...); err != nil {
+		return result, err
+	}
+	// commit only the imported files, even if others are staged
+	args := append([]string{"commit", "-q", "-m", result.Message, "--"}, changed...)
+	if err := im.siteGit(ctx, cfg.SiteRoot, nil, args...); err != nil {
+		return result, err
+	}
+	im.logf("Committed %d changed files to branch %q\n", len(changed), result.Branch)
+	return result, nil
+}
+
+// commitKey starts the line of a repo in the commit message, e.g.
+// "kubernetes release-1.10: ", or "kubernetes release-1.10 (v1.10): " with
+// releases.
+func commitKey(rr RepoReport) string {
+	if rr.Version != "" {
+		return fmt.Sprintf("%s %s (%s): ", rr.Name, rr.Branch, rr.Version)
+	}
+	return fmt.Sprintf("%s %s: ", rr.Name, rr.Branch)
+}
+
+// changedFiles returns the paths that differ from HEAD in the site, or are
+// not tracked yet.
+func (im *Importer) changedFiles(ctx context.Context, siteRoot string, paths []string) ([]string, error) {
+	var out bytes.Buffer
+	args := append([]string{"status", "--porcelain", "-z", "--untracked-files=all", "--"}, paths...)
+	if err := im.siteGit(ctx, siteRoot, &out, args...); err != nil {
+		return nil, err
+	}
+	var changed []string
+	entries := strings.Split(out.String(), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		changed = append(changed, entry[3:])
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // skip the source of a staged rename or copy
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// lastImport returns the commit that key was last imported at, according
+// to the commit messages of the site, or "unknown".
+func (im *Importer) lastImport(ctx context.Context, siteRoot string, key string) string {
+	var out bytes.Buffer
+	// fails in a repo without commits, which has no earlier import either
+	if err := im.siteGit(ctx, siteRoot, &out, "log", "-1", "--format=%B", "-F", "--grep", key); err != nil {
+		return "unknown"
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, key) {
+			if i := strings.Index(line, ".."); i >= 0 {
+				return line[i+2:]
+			}
+		}
+	}
+	return "unknown"
+}
+
+func (im *Importer) siteGit(ctx context.Context, siteRoot string, out *bytes.Buffer, args ...string) error {
+	if out == nil {
+		out = &bytes.Buffer{}
+	}
+	return im.Exec.Run(ctx, siteRoot, out, "git", args...)
+}
@@ -0,0 +1,109 @@
+package importer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	site, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(site)
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+		defer os.Setenv(v, os.Getenv(v))
+		os.Setenv(v, "test")
+	}
+
+	im := &Importer{Exec: OSRunner{}, FS: OSFS{}}
+	ctx := context.Background()
+	write := func(name, content string) {
+		if err := im.writeSiteFile(site, name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", site}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	git("checkout", "-q", "-b", "master")
+	write("docs/imported/a.md", "a\n")
+	write("docs/imported/b.md", "b\n")
+	write("docs/other.md", "other\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "Update imported docs from community.yml\n\ncommunity master: unknown..1111111\n")
+
+	// a.md changes, b.md does not, c.md is new and other.md is not imported
+	write("docs/imported/a.md", "a2\n")
+	write("docs/imported/c.md", "c\n")
+	write("docs/other.md", "other2\n")
+	git("add", "docs/other.md")
+
+	cfg := Config{SiteRoot: site}
+	report := Report{Repos: []RepoReport{{
+		Name:   "community",
+		Branch: "master",
+		Commit: "2222222",
+		Files:  []string{"docs/imported/a.md", "docs/imported/b.md", "docs/imported/c.md"},
+	}}}
+	date := time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC)
+	result, err := im.Commit(ctx, cfg, report, CommitOptions{ConfigName: "community.yml", Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Branch != "imported-docs/community-2018-04-01" {
+		t.Errorf("unexpected branch %q", result.Branch)
+	}
+	wantMessage := "Update imported docs from community.yml\n\n" +
+		"community master: 1111111..2222222\n\n" +
+		"Changed files:\n  docs/imported/a.md\n  docs/imported/c.md\n"
+	if result.Message != wantMessage {
+		t.Errorf("unexpected message:\n%s\nwant:\n%s", result.Message, wantMessage)
+	}
+	if got := git("show", "--name-only", "--format=", "HEAD"); got != "docs/imported/a.md\ndocs/imported/c.md\n" {
+		t.Errorf("unexpected committed files:\n%s", got)
+	}
+	if got := git("rev-parse", "--abbrev-ref", "HEAD"); got != result.Branch+"\n" {
+		t.Errorf("expected to be on %s, on %s", result.Branch, got)
+	}
+	// the staged change that was not imported is left alone
+	if got := git("diff", "--cached", "--name-only"); got != "docs/other.md\n" {
+		t.Errorf("unexpected staged files:\n%s", got)
+	}
+
+	// a second run on the same day gets its own branch
+	write("docs/imported/b.md", "b2\n")
+	report.Repos[0].Commit = "3333333"
+	result, err = im.Commit(ctx, cfg, report, CommitOptions{ConfigName: "community.yml", Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Branch != "imported-docs/community-2018-04-01-2" {
+		t.Errorf("unexpected branch %q", result.Branch)
+	}
+	if !strings.Contains(result.Message, "community master: 2222222..3333333\n") {
+		t.Errorf("expected the commit of the last import in:\n%s", result.Message)
+	}
+
+	// nothing changed
+	result, err = im.Commit(ctx, cfg, report, CommitOptions{ConfigName: "community.yml", Date: date})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Branch != "" || len(result.Files) != 0 {
+		t.Errorf("expected no commit, got %+v", result)
+	}
+}
@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// Config is the contents of an update-imported-docs config file, plus the
+// directories an import run works in.
+type Config struct {
+	// SiteRoot is the root directory of the website checkout.
+	SiteRoot string `json:"-"`
+	// WorkDir is a scratch directory for clones. It is emptied by Run.
+	WorkDir string `json:"-"`
+	// KeepGoing imports the other repos when one fails, instead of stopping.
+	KeepGoing bool `json:"-"`
+
+	Repos []Repo `json:"repos"`
+
+	// Releases, when set, imports every repo once per release into a
+	// directory named after the version, below VersionedRoot.
+	Releases      []Release `json:"releases,omitempty"`
+	VersionedRoot string    `json:"versioned-root,omitempty"`
+	// VersionIndex is an optional data file listing the imported releases.
+	VersionIndex string `json:"version-index,omitempty"`
+
+	// AssetsDir, when set, is where local images and other assets referenced
+	// by imported files are copied to, in a subdirectory per repo, e.g.
+	// images/imported.
+	AssetsDir string `json:"assets-dir,omitempty"`
+}
+
+// Repo is one upstream repository to import files from.
+type Repo struct {
+	// Name is used as the directory name of the clone.
+	Name   string `json:"name"`
+	Remote string `json:"remote"`
+	Branch string `json:"branch"`
+	// GenerateCommand is run from the clone before files are copied,
+	// e.g. "hack/generate-docs.sh".
+	GenerateCommand  string `json:"generate-command,omitempty"`
+	GenAbsoluteLinks bool   `json:"gen-absolute-links,omitempty"`
+	Files            []File `json:"files"`
+
+	// FullClone checks out the whole tree instead of only the files that are
+	// imported. It defaults to true for repos with a GenerateCommand or a
+	// Generator, which usually need the whole tree, and to false otherwise.
+	FullClone *bool `json:"full-clone,omitempty"`
+	// SparsePaths are extra paths or globs to check out besides the `src`
+	// files, e.g. what a GenerateCommand needs when FullClone is false.
+	SparsePaths []string `json:"sparse-paths,omitempty"`
+
+	// Generator is empty, or GeneratorCobra to generate reference pages for
+	// Commands from the Go sources of the clone, after GenerateCommand.
+	Generator string         `json:"generator,omitempty"`
+	Commands  []CobraCommand `json:"commands,omitempty"`
+}
+
+// sparsePaths returns the patterns to limit the checkout of r to, or nil if
+// r needs a full clone. withAssets adds the assets that may be imported.
+func (r Repo) sparsePaths(withAssets bool) []string {
+	full := r.GenerateCommand != "" || r.Generator != ""
+	if r.FullClone != nil {
+		full = *r.FullClone
+	}
+	if full {
+		return nil
+	}
+	var paths []string
+	for _, f := range r.Files {
+		paths = append(paths, f.Src)
+	}
+	if withAssets {
+		paths = append(paths, assetSparsePaths()...)
+	}
+	return append(paths, r.SparsePaths...)
+}
+
+// File maps a file in the upstream repo to its place in the website.
+type File struct {
+	Src string `json:"src"`
+	Dst string `json:"dst"`
+	// Mode is empty to copy the file, ModeChangelog to split it into a page
+	// per release, ModeKEPs to read the KEPs below the directory Src into
+	// the data file Dst, ModeOpenAPI to write a page per definition of
+	// the spec Src below the directory Dst, or ModeHTML to sanitize the HTML
+	// file Src.
+	Mode string `json:"mode,omitempty"`
+	// Stubs is the directory for a stub page per KEP, for ModeKEPs.
+	Stubs string `json:"stubs,omitempty"`
+	// TOC is a data file for the table of contents of the pages, for
+	// ModeOpenAPI.
+	TOC string `json:"toc,omitempty"`
+	// Markdown converts the file to Markdown, for ModeHTML.
+	Markdown bool `json:"markdown,omitempty"`
+}
+
+// Release describes one entry of the optional `releases` list.
+type Release struct {
+	// Version is used as the directory name, e.g. "v1.10"
+	Version string `json:"version"`
+	// Branches maps a repo name to the branch to clone for this release.
+	// Repos that are not listed keep the branch from their own config.
+	Branches map[string]string `json:"branches,omitempty"`
+}
+
+// LoadConfig reads and validates the config file at filename.
+func LoadConfig(filename string) (Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Config{}, &ConfigError{File: filename, Err: err}
+	}
+	cfg, err := ParseConfig(content)
+	if err != nil {
+		if cerr, ok := err.(*ConfigError); ok {
+			cerr.File = filename
+		}
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses and validates the contents of a config file.
+func ParseConfig(content []byte) (Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return Config{}, &ConfigError{Err: err}
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Validate checks that the config can be imported.
+func (c Config) Validate() error {
+	if len(c.Repos) == 0 {
+		return &ConfigError{Err: fmt.Errorf("no repos listed")}
+	}
+	names := make(map[string]bool)
+	for _, r := range c.Repos {
+		if r.Name == "" || strings.Contains(r.Name, "/") {
+			return &ConfigError{Err: fmt.Errorf("invalid repo name %q", r.Name)}
+		}
+		if names[r.Name] {
+			return &ConfigError{Err: fmt.Errorf("repo %q is listed more than once", r.Name)}
+		}
+		names[r.Name] = true
+		if _, err := remotePrefix(r.Remote, r.Branch); err != nil {
+			return &ConfigError{Err: err}
+		}
+		for _, f := range r.Files {
+			if f.Src == "" || f.Dst == "" {
+				return &ConfigError{Err: fmt.Errorf("repo %q: every file needs a src and a dst", r.Name)}
+			}
+			if f.Mode != "" && f.Mode != ModeChangelog && f.Mode != ModeKEPs && f.Mode != ModeOpenAPI && f.Mode != ModeHTML {
+				return &ConfigError{Err: fmt.Errorf("repo %q: unknown mode %q for %q", r.Name, f.Mode, f.Src)}
+			}
+		}
+		if r.Generator != "" && r.Generator != GeneratorCobra {
+			return &ConfigError{Err: fmt.Errorf("repo %q: unknown generator %q", r.Name, r.Generator)}
+		}
+		if r.Generator == "" && len(r.Commands) > 0 {
+			return &ConfigError{Err: fmt.Errorf("repo %q: `commands` need `generator: cobra`", r.Name)}
+		}
+		for _, c := range r.Commands {
+			if c.Command == "" || c.Dst == "" {
+				return &ConfigError{Err: fmt.Errorf("repo %q: every command needs a command and a dst", r.Name)}
+			}
+			for _, spec := range c.Imports {
+				if err := checkImport(spec); err != nil {
+					return &ConfigError{Err: fmt.Errorf("repo %q: %v", r.Name, err)}
+				}
+			}
+		}
+	}
+	if c.AssetsDir != "" && (path.IsAbs(c.AssetsDir) || strings.HasPrefix(path.Clean(c.AssetsDir), "..")) {
+		return &ConfigError{Err: fmt.Errorf("assets-dir %q must be inside the site", c.AssetsDir)}
+	}
+	if len(c.Releases) == 0 {
+		return nil
+	}
+	if c.VersionedRoot == "" {
+		return &ConfigError{Err: fmt.Errorf("a config with `releases` must also set `versioned-root`, e.g. docs/reference/generated")}
+	}
+	for _, rel := range c.Releases {
+		if rel.Version == "" || strings.Contains(rel.Version, "/") {
+			return &ConfigError{Err: fmt.Errorf("invalid release version %q", rel.Version)}
+		}
+		for _, r := range c.Repos {
+			for _, f := range r.Files {
+				if _, err := versionedPath(f.Dst, path.Join(c.VersionedRoot, rel.Version)); err != nil {
+					return &ConfigError{Err: err}
+				}
+			}
+			for _, cmd := range r.Commands {
+				if _, err := versionedPath(cmd.Dst, path.Join(c.VersionedRoot, rel.Version)); err != nil {
+					return &ConfigError{Err: err}
+				}
+			}
+		}
+	}
+	return nil
+}
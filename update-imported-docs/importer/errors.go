@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// ConfigError reports a config file that cannot be read or is invalid.
+type ConfigError struct {
+	File string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("invalid config: %v", e.Err)
+	}
+	return fmt.Sprintf("invalid config %s: %v", e.File, e.Err)
+}
+
+// Operations reported by RepoError.
+const (
+	OpClone    = "clone"
+	OpGenerate = "generate"
+	OpCopy     = "copy"
+)
+
+// RepoError reports a failure while importing a single repo.
+type RepoError struct {
+	Repo string
+	// Op is one of OpClone, OpGenerate or OpCopy.
+	Op string
+	// File is the `src` being copied, for OpCopy.
+	File string
+	Err  error
+}
+
+func (e *RepoError) Error() string {
+	switch e.Op {
+	case OpClone:
+		return fmt.Sprintf("error when cloning repo %q: %v", e.Repo, e.Err)
+	case OpGenerate:
+		return fmt.Sprintf("error when generating docs for repo %q: %v", e.Repo, e.Err)
+	default:
+		return fmt.Sprintf("error when copying %q from repo %q: %v", e.File, e.Repo, e.Err)
+	}
+}
+
+// LinkError lists the imported files with broken anchors or site links. The
+// files have been written when it is returned.
+type LinkError struct {
+	// Files maps a dst path to its problems.
+	Files map[string][]string
+}
+
+func (e *LinkError) Error() string {
+	var files []string
+	for file := range e.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d imported files have broken links:", len(files))
+	for _, file := range files {
+		fmt.Fprintf(&b, "\n%s:", file)
+		for _, problem := range e.Files[file] {
+			fmt.Fprintf(&b, "\n\t%s", problem)
+		}
+	}
+	return b.String()
+}
+
+// ImportError lists the repos that failed in a run with KeepGoing. The other
+// repos have been imported when it is returned.
+type ImportError struct {
+	Failures []RepoFailure
+}
+
+func (e *ImportError) Error() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%d repos failed to import:", len(e.Failures))
+	for _, f := range e.Failures {
+		fmt.Fprintf(&b, "\n%s at %q", f.Name, f.Branch)
+		if f.Version != "" {
+			fmt.Fprintf(&b, " for release %q", f.Version)
+		}
+		fmt.Fprintf(&b, ":\n\t%v", f.Err)
+	}
+	return b.String()
+}
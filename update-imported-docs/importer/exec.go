@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// Runner runs external commands.
+type Runner interface {
+	// Run runs name with args from dir, writing its standard output to
+	// stdout.
+	Run(ctx context.Context, dir string, stdout io.Writer, name string, args ...string) error
+}
+
+// OSRunner runs commands with os/exec.
+type OSRunner struct{}
+
+// Run implements Runner.
+func (OSRunner) Run(ctx context.Context, dir string, stdout io.Writer, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// prefixWriter writes every line with a prefix, to display the running
+// output of a command.
+type prefixWriter struct {
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := io.WriteString(p.w, p.prefix); err != nil {
+			return 0, err
+		}
+		if _, err := p.w.Write(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Flush writes out a last line that does not end with a newline.
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		io.WriteString(p.w, p.prefix)
+		p.w.Write(p.buf)
+		io.WriteString(p.w, "\n")
+		p.buf = nil
+	}
+}
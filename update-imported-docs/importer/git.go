@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Git performs the git operations an import needs.
+type Git interface {
+	// Clone makes a shallow clone of branch of remote into dir.
+	Clone(ctx context.Context, remote, branch, dir string, opts CloneOptions) error
+	// Head returns the SHA of the commit checked out in the clone at dir.
+	Head(ctx context.Context, dir string) (string, error)
+}
+
+// CloneOptions configures a clone.
+type CloneOptions struct {
+	// SparsePaths limits the checkout to these paths and globs, relative to
+	// the root of the repo. Blobs outside of them are not downloaded. An
+	// empty list checks out the whole tree.
+	SparsePaths []string
+}
+
+// NewGit returns a Git that runs the git binary with r. It logs to log when
+// it cannot clone sparsely.
+func NewGit(r Runner, log io.Writer) Git {
+	if log == nil {
+		log = ioutil.Discard
+	}
+	return &execGit{runner: r, log: log}
+}
+
+type execGit struct {
+	runner Runner
+	log    io.Writer
+
+	sparseOnce sync.Once
+	sparse     bool
+}
+
+func (g *execGit) Clone(ctx context.Context, remote, branch, dir string, opts CloneOptions) error {
+	if len(opts.SparsePaths) == 0 {
+		return g.git(ctx, "", "clone", "--depth=1", "-b", branch, remote, dir)
+	}
+	g.sparseOnce.Do(func() {
+		g.sparse = SparseCheckoutSupported(ctx, g.runner)
+		if !g.sparse {
+			fmt.Fprintf(g.log, "git is older than 2.35 and cannot check out sparse paths, making full clones instead\n")
+		}
+	})
+	if !g.sparse {
+		return g.git(ctx, "", "clone", "--depth=1", "-b", branch, remote, dir)
+	}
+
+	// Partial clone without blobs, then only check out the sparse paths.
+	// Servers without partial clone support send all blobs instead.
+	err := g.git(ctx, "", "clone", "--depth=1", "--filter=blob:none", "--no-checkout", "-b", branch, remote, dir)
+	if err != nil {
+		return err
+	}
+	// Anchor the patterns at the root of the repo
+	args := []string{"sparse-checkout", "set", "--no-cone"}
+	for _, p := range opts.SparsePaths {
+		args = append(args, "/"+strings.TrimPrefix(p, "/"))
+	}
+	if err := g.git(ctx, dir, args...); err != nil {
+		fmt.Fprintf(g.log, "Sparse checkout of %q failed (%v), making a full clone instead\n", remote, err)
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		return g.git(ctx, "", "clone", "--depth=1", "-b", branch, remote, dir)
+	}
+	return g.git(ctx, dir, "checkout", branch)
+}
+
+// SparseCheckoutSupported reports whether the git binary run by r supports
+// non-cone sparse checkouts, which need git 2.35.
+func SparseCheckoutSupported(ctx context.Context, r Runner) bool {
+	var out bytes.Buffer
+	if err := r.Run(ctx, "", &out, "git", "version"); err != nil {
+		return false
+	}
+	return gitAtLeast(out.String(), 2, 35)
+}
+
+// gitAtLeast reports whether the output of git version, such as
+// "git version 2.39.5" or "git version 2.30.1 (Apple Git-130)", is of
+// major.minor or newer.
+func gitAtLeast(version string, major, minor int) bool {
+	var gotMajor, gotMinor int
+	if _, err := fmt.Sscanf(strings.TrimSpace(version), "git version %d.%d", &gotMajor, &gotMinor); err != nil {
+		return false
+	}
+	return gotMajor > major || gotMajor == major && gotMinor >= minor
+}
+
+func (g *execGit) Head(ctx context.Context, dir string) (string, error) {
+	var out bytes.Buffer
+	if err := g.runner.Run(ctx, dir, &out, "git", "rev-parse", "HEAD"); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+func (g *execGit) git(ctx context.Context, dir string, args ...string) error {
+	return g.runner.Run(ctx, dir, ioutil.Discard, "git", args...)
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// gitRunner records the git commands it runs, prints version for git
+// version, and fails the sparse-checkout command if failSparse is set.
+type gitRunner struct {
+	version    string
+	failSparse bool
+	ran        []string
+}
+
+func (r *gitRunner) Run(ctx context.Context, dir string, stdout io.Writer, name string, args ...string) error {
+	r.ran = append(r.ran, strings.Join(args, " "))
+	switch args[0] {
+	case "version":
+		io.WriteString(stdout, r.version)
+	case "sparse-checkout":
+		if r.failSparse {
+			return errors.New("exit status 129")
+		}
+	}
+	return nil
+}
+
+func TestGitAtLeast(t *testing.T) {
+	for version, want := range map[string]bool{
+		"git version 2.35.0\n":               true,
+		"git version 2.39.5":                 true,
+		"git version 3.0.1":                  true,
+		"git version 2.30.1 (Apple Git-130)": false,
+		"git version 2.34.1.windows.1":       false,
+		"git version 1.40.0":                 false,
+		"not git":                            false,
+	} {
+		if got := gitAtLeast(version, 2, 35); got != want {
+			t.Errorf("gitAtLeast(%q, 2, 35) = %v, want %v", version, got, want)
+		}
+	}
+}
+
+func TestCloneFallsBackToFullClone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	opts := CloneOptions{SparsePaths: []string{"docs/"}}
+	full := "clone --depth=1 -b master https://example.com/repo.git " + dir
+	for _, tc := range []struct {
+		name   string
+		runner *gitRunner
+		want   string
+	}{
+		{"sparse", &gitRunner{version: "git version 2.39.5"}, "checkout master"},
+		{"old git", &gitRunner{version: "git version 2.25.1"}, full},
+		{"failed sparse checkout", &gitRunner{version: "git version 2.39.5", failSparse: true}, full},
+	} {
+		var log bytes.Buffer
+		g := NewGit(tc.runner, &log)
+		if err := g.Clone(context.Background(), "https://example.com/repo.git", "master", dir, opts); err != nil {
+			t.Fatalf("%s: Clone: %v", tc.name, err)
+		}
+		if last := tc.runner.ran[len(tc.runner.ran)-1]; last != tc.want {
+			t.Errorf("%s: last ran %q, want %q", tc.name, last, tc.want)
+		}
+		if fellBack := tc.want == full; fellBack != (log.Len() > 0) {
+			t.Errorf("%s: unexpected log %q", tc.name, log.String())
+		}
+	}
+}
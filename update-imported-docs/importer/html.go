@@ -0,0 +1,458 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"golang.org/x/net/html"
+)
+
+// ModeHTML sanitizes an HTML file, and converts it to Markdown if
+// File.Markdown is set, see File.Mode.
+const ModeHTML = "html"
+
+// droppedTags are removed from imported HTML together with their content.
+var droppedTags = map[string]bool{
+	"base": true, "button": true, "embed": true, "form": true, "head": true,
+	"iframe": true, "input": true, "link": true, "meta": true, "noscript": true,
+	"object": true, "script": true, "select": true, "style": true,
+	"svg": true, "textarea": true, "title": true,
+}
+
+// allowedTags are kept in imported HTML. Other tags are replaced by their
+// content.
+var allowedTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "blockquote": true, "br": true,
+	"caption": true, "code": true, "col": true, "colgroup": true, "dd": true,
+	"del": true, "div": true, "dl": true, "dt": true, "em": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true,
+	"i": true, "img": true, "ins": true, "kbd": true, "li": true, "ol": true,
+	"p": true, "pre": true, "s": true, "small": true, "span": true,
+	"strong": true, "sub": true, "sup": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "tr": true,
+	"u": true, "ul": true,
+}
+
+// allowedAttrs are the attributes kept per tag, besides id and title.
+var allowedAttrs = map[string]map[string]bool{
+	"a":   {"href": true, "name": true},
+	"img": {"src": true, "alt": true, "width": true, "height": true},
+	"td":  {"colspan": true, "rowspan": true},
+	"th":  {"colspan": true, "rowspan": true},
+	"ol":  {"start": true},
+}
+
+// htmlBlockTags start a new block when converting to Markdown.
+var htmlBlockTags = map[string]bool{
+	"blockquote": true, "div": true, "dl": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "hr": true, "ol": true,
+	"p": true, "pre": true, "table": true, "ul": true,
+}
+
+// importHTML sanitizes the HTML content of f, rewrites its links like
+// processLinks if the repo asks for absolute links, and writes it to dst,
+// as Markdown if f.Markdown is set. Without a title block, the title of the
+// page is taken from its <title> or first <h1>. It returns the written
+// paths, relative to the site root.
+func (im *Importer) importHTML(cfg Config, repo Repo, f File, prefix string, dst string, titleBlock []byte, content []byte) ([]string, error) {
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	title := ""
+	if t := findElement(doc, "title"); t != nil {
+		title = collapseSpace(textContent(t))
+	}
+	body := findElement(doc, "body")
+	if body == nil {
+		return nil, fmt.Errorf("no HTML body found")
+	}
+
+	var rewrite func(string) string
+	if repo.GenAbsoluteLinks {
+		sitePrefix := ""
+		if cfg.AssetsDir != "" {
+			sitePrefix = "/" + path.Clean(cfg.AssetsDir) + "/"
+		}
+		rewrite = func(url string) string {
+			return rewriteLink(url, prefix, path.Dir(f.Src), sitePrefix)
+		}
+	}
+	sanitizeHTML(body, rewrite)
+
+	// the layout shows the title, so drop a first heading that repeats it
+	if h1 := findElement(body, "h1"); h1 != nil {
+		text := collapseSpace(textContent(h1))
+		if title == "" {
+			title = text
+		}
+		if text == title {
+			parent := h1.Parent
+			parent.RemoveChild(h1)
+			// and the header around it, if nothing else is left
+			if parent != body && parent.Data == "div" && strings.TrimSpace(textContent(parent)) == "" {
+				parent.Parent.RemoveChild(parent)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if titleBlock != nil {
+		out.Write(titleBlock)
+	} else if title != "" {
+		titleLine, err := yaml.Marshal(map[string]string{"title": title})
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString("---\n")
+		out.Write(titleLine)
+		out.WriteString("---\n")
+	}
+	// the HTML may contain Go templates, which are not Liquid
+	out.WriteString("{% raw %}\n")
+	if f.Markdown {
+		out.Write(htmlToMarkdown(body))
+	} else {
+		var rendered bytes.Buffer
+		for c := body.FirstChild; c != nil; c = c.NextSibling {
+			if err := html.Render(&rendered, c); err != nil {
+				return nil, err
+			}
+		}
+		out.Write(bytes.TrimSpace(rendered.Bytes()))
+		out.WriteString("\n")
+	}
+	out.WriteString("{% endraw %}\n")
+
+	if err := im.writeSiteFile(cfg.SiteRoot, dst, out.Bytes()); err != nil {
+		return nil, err
+	}
+	return []string{dst}, nil
+}
+
+// sanitizeHTML removes comments and the dropped tags below n, unwraps tags
+// that are not allowed and drops their attributes that are not allowed.
+// rewrite, if set, is applied to the links that are kept.
+func sanitizeHTML(n *html.Node, rewrite func(string) string) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode, html.DoctypeNode:
+			n.RemoveChild(c)
+		case html.ElementNode:
+			tag := strings.ToLower(c.Data)
+			if droppedTags[tag] {
+				n.RemoveChild(c)
+				break
+			}
+			sanitizeHTML(c, rewrite)
+			if !allowedTags[tag] {
+				for gc := c.FirstChild; gc != nil; gc = c.FirstChild {
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+				}
+				n.RemoveChild(c)
+				break
+			}
+			var attrs []html.Attribute
+			for _, a := range c.Attr {
+				key := strings.ToLower(a.Key)
+				if a.Namespace != "" || (key != "id" && key != "title" && !allowedAttrs[tag][key]) {
+					continue
+				}
+				if key == "href" || key == "src" {
+					if !safeURL(a.Val) {
+						continue
+					}
+					if rewrite != nil {
+						a.Val = rewrite(strings.TrimSpace(a.Val))
+					}
+				}
+				attrs = append(attrs, a)
+			}
+			c.Attr = attrs
+		}
+		c = next
+	}
+}
+
+// safeURL reports whether url is relative, or uses a scheme that is safe to
+// link to.
+func safeURL(url string) bool {
+	url = strings.ToLower(strings.TrimSpace(url))
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		return true
+	}
+	switch url[:colon] {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func findElement(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, tag); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b bytes.Buffer
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// htmlToMarkdown converts the children of the sanitized node n to kramdown
+// Markdown.
+func htmlToMarkdown(n *html.Node) []byte {
+	var b bytes.Buffer
+	writeMarkdownBlocks(&b, n)
+	return append(bytes.TrimSpace(b.Bytes()), '\n')
+}
+
+// writeMarkdownBlocks writes the children of n as blocks separated by blank
+// lines. Runs of inline children become paragraphs.
+func writeMarkdownBlocks(b *bytes.Buffer, n *html.Node) {
+	var para []*html.Node
+	flush := func() {
+		var text bytes.Buffer
+		for _, c := range para {
+			text.WriteString(markdownInline(c))
+		}
+		if s := collapseSpace(text.String()); s != "" {
+			b.WriteString(s)
+			b.WriteString("\n\n")
+		}
+		para = nil
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || !htmlBlockTags[c.Data] {
+			para = append(para, c)
+			continue
+		}
+		flush()
+		switch c.Data {
+		case "p", "div":
+			writeMarkdownBlocks(b, c)
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			if text := collapseSpace(markdownInlineChildren(c)); text != "" {
+				// keep the ID, which links may point to
+				if id := getAttr(c, "id"); id != "" {
+					text += " {#" + id + "}"
+				}
+				fmt.Fprintf(b, "%s %s\n\n", strings.Repeat("#", int(c.Data[1]-'0')), text)
+			}
+		case "pre":
+			fmt.Fprintf(b, "```\n%s\n```\n\n", strings.Trim(textContent(c), "\n"))
+		case "blockquote":
+			var quote bytes.Buffer
+			writeMarkdownBlocks(&quote, c)
+			for _, line := range strings.Split(strings.TrimSpace(quote.String()), "\n") {
+				b.WriteString(strings.TrimSpace("> " + line))
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		case "ul", "ol":
+			writeMarkdownList(b, c)
+		case "dl":
+			for item := c.FirstChild; item != nil; item = item.NextSibling {
+				if item.Type != html.ElementNode {
+					continue
+				}
+				text := collapseSpace(markdownInlineChildren(item))
+				if item.Data == "dd" {
+					text = ": " + text
+				}
+				b.WriteString(text)
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		case "table":
+			writeMarkdownTable(b, c)
+		case "hr":
+			b.WriteString("* * *\n\n")
+		}
+	}
+	flush()
+}
+
+// writeMarkdownList writes the <li> children of list, with nested blocks
+// indented below their item. Ordered lists are numbered.
+func writeMarkdownList(b *bytes.Buffer, list *html.Node) {
+	number := 1
+	for item := list.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.Data != "li" {
+			continue
+		}
+		marker := "* "
+		if list.Data == "ol" {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		var content bytes.Buffer
+		writeMarkdownBlocks(&content, item)
+		indent := strings.Repeat(" ", len(marker))
+		lines := strings.Split(strings.TrimSpace(content.String()), "\n")
+		for i, line := range lines {
+			switch {
+			case line == "" && i+1 < len(lines) && listItemRegex.MatchString(lines[i+1]):
+				// keep a nested list tight
+				continue
+			case i == 0:
+				b.WriteString(marker + line)
+			case line != "":
+				b.WriteString(indent + line)
+			}
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("\n")
+}
+
+// writeMarkdownTable writes table as a table with its first row as header.
+// Cells are flattened to inline Markdown.
+func writeMarkdownTable(b *bytes.Buffer, table *html.Node) {
+	var rows [][]string
+	columns := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			case "tr":
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						row = append(row, tableCell(markdownInlineChildren(cell)))
+					}
+				}
+				if len(row) > columns {
+					columns = len(row)
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	walk(table)
+	if len(rows) == 0 {
+		return
+	}
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(row, " | "))
+		if i == 0 {
+			b.WriteString(strings.Repeat("| --- ", columns) + "|\n")
+		}
+	}
+	b.WriteString("\n")
+}
+
+func markdownInlineChildren(n *html.Node) string {
+	var b bytes.Buffer
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(markdownInline(c))
+	}
+	return b.String()
+}
+
+// markdownInline converts n to inline Markdown. Block elements are
+// flattened to their content.
+func markdownInline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escapeMarkdown(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	wrap := func(delim string) string {
+		text := strings.TrimSpace(markdownInlineChildren(n))
+		if text == "" {
+			return ""
+		}
+		return delim + text + delim
+	}
+	switch n.Data {
+	case "a":
+		text := strings.TrimSpace(markdownInlineChildren(n))
+		href := getAttr(n, "href")
+		if href == "" {
+			// keep named anchors, which links on the page may point to
+			name := getAttr(n, "name")
+			if name == "" {
+				name = getAttr(n, "id")
+			}
+			if name != "" {
+				return `<a name="` + html.EscapeString(name) + `"></a>` + text
+			}
+			return text
+		}
+		if text == "" {
+			text = escapeMarkdown(href)
+		}
+		return fmt.Sprintf("[%s](%s)", text, href)
+	case "img":
+		return fmt.Sprintf("![%s](%s)", escapeMarkdown(getAttr(n, "alt")), getAttr(n, "src"))
+	case "strong", "b":
+		return wrap("**")
+	case "em", "i":
+		return wrap("*")
+	case "code", "kbd":
+		text := collapseSpace(textContent(n))
+		if text == "" {
+			return ""
+		}
+		return "`" + text + "`"
+	case "br":
+		return "<br>"
+	case "abbr", "del", "ins", "s", "small", "sub", "sup", "u":
+		return fmt.Sprintf("<%s>%s</%s>", n.Data, markdownInlineChildren(n), n.Data)
+	}
+	if htmlBlockTags[n.Data] || n.Data == "li" || n.Data == "dt" || n.Data == "dd" {
+		return " " + markdownInlineChildren(n) + " "
+	}
+	return markdownInlineChildren(n)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "`", "\\`", "<", `\<`,
+)
+
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const testHTML = `<!DOCTYPE html>
+<html>
+<head>
+<title>Top Level API Objects</title>
+<style>body { color: red; }</style>
+<script>alert(1)</script>
+</head>
+<body class="article">
+<div id="header"><h1>Top Level API Objects</h1></div>
+<div class="sect1">
+<h2 id="_v1_cluster">v1.Cluster</h2>
+<p style="color: red" onclick="alert(1)">A <em>cluster</em>, see <a href="../README.md">the README</a>
+and <a href="javascript:alert(1)">this</a>.</p>
+<!-- a comment -->
+<table class="tableblock">
+<thead><tr><th>Name</th><th>Description</th></tr></thead>
+<tbody><tr><td><p>spec</p></td><td><p>The <code>spec</code> | or <a href="#_v1_clusterspec">ClusterSpec</a></p></td></tr></tbody>
+</table>
+<ul><li>one<ul><li>nested</li></ul></li><li>two &lt;x&gt;</li></ul>
+<pre>kubectl get clusters
+</pre>
+</div>
+</body>
+</html>
+`
+
+func TestImportHTML(t *testing.T) {
+	site, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(site)
+	cfg := Config{SiteRoot: site}
+	repo := Repo{GenAbsoluteLinks: true}
+	prefix := "https://github.com/kubernetes/federation/tree/master"
+
+	cases := []struct {
+		file File
+		want string
+	}{
+		{
+			file: File{Src: "docs/api-reference/v1/definitions.html", Dst: "docs/federation/definitions.html"},
+			want: `---
+title: Top Level API Objects
+---
+{% raw %}
+<div>
+<h2 id="_v1_cluster">v1.Cluster</h2>
+<p>A <em>cluster</em>, see <a href="https://github.com/kubernetes/federation/tree/master/docs/api-reference/v1/../README.md">the README</a>
+and <a>this</a>.</p>
+
+<table>
+<thead><tr><th>Name</th><th>Description</th></tr></thead>
+<tbody><tr><td><p>spec</p></td><td><p>The <code>spec</code> | or <a href="#_v1_clusterspec">ClusterSpec</a></p></td></tr></tbody>
+</table>
+<ul><li>one<ul><li>nested</li></ul></li><li>two &lt;x&gt;</li></ul>
+<pre>kubectl get clusters
+</pre>
+</div>
+{% endraw %}
+`,
+		},
+		{
+			file: File{Src: "docs/api-reference/v1/definitions.html", Dst: "docs/federation/definitions.md", Markdown: true},
+			want: `---
+title: Top Level API Objects
+---
+{% raw %}
+## v1.Cluster {#_v1_cluster}
+
+A *cluster*, see [the README](https://github.com/kubernetes/federation/tree/master/docs/api-reference/v1/../README.md) and this.
+
+| Name | Description |
+| --- | --- |
+| spec | The ` + "`spec`" + ` \| or [ClusterSpec](#_v1_clusterspec) |
+
+* one
+  * nested
+* two \<x>
+
+` + "```" + `
+kubectl get clusters
+` + "```" + `
+{% endraw %}
+`,
+		},
+	}
+	im := &Importer{FS: OSFS{}}
+	for _, c := range cases {
+		written, err := im.importHTML(cfg, repo, c.file, prefix, c.file.Dst, nil, []byte(testHTML))
+		if err != nil {
+			t.Fatalf("%s: %v", c.file.Dst, err)
+		}
+		if len(written) != 1 || written[0] != c.file.Dst {
+			t.Errorf("%s: unexpected written files %v", c.file.Dst, written)
+		}
+		got := readStamped(t, filepath.Join(site, c.file.Dst))
+		if got != c.want {
+			t.Errorf("%s: unexpected content:\n%s\nwant:\n%s", c.file.Dst, got, c.want)
+		}
+	}
+}
+
+func TestMarkdownNamedAnchor(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<p><a name='say "hi" &amp; go'></a>Hello</p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.TrimSpace(string(htmlToMarkdown(findElement(doc, "body"))))
+	want := `<a name="say &#34;hi&#34; &amp; go"></a>Hello`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	for url, want := range map[string]bool{
+		"https://kubernetes.io/":   true,
+		"mailto:a@example.com":     true,
+		"../README.md":             true,
+		"#anchor":                  true,
+		"docs/a:b.md":              true,
+		"javascript:alert(1)":      false,
+		" JavaScript:alert(1)":     false,
+		"data:text/html;base64,xx": false,
+	} {
+		if got := safeURL(url); got != want {
+			t.Errorf("safeURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Package importer copies docs from other Kubernetes repos into the website,
+// as described by an update-imported-docs config file.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// Importer imports the repos of a Config. The zero value is not usable; use
+// New, then replace any of the fields.
+type Importer struct {
+	Git Git
+	// Exec runs the generate-command of a repo, and git in the site for
+	// Commit.
+	Exec Runner
+	FS   FS
+	// Log receives progress messages. It may be nil.
+	Log io.Writer
+	// Retry configures how failed clones are retried.
+	Retry RetryPolicy
+
+	// sleep replaces the wait between retries in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New returns an Importer that uses the local git binary and filesystem, and
+// logs to out.
+func New(out io.Writer) *Importer {
+	return &Importer{
+		Git:   NewGit(OSRunner{}, out),
+		Exec:  OSRunner{},
+		FS:    OSFS{},
+		Log:   out,
+		Retry: DefaultRetryPolicy,
+	}
+}
+
+// Report describes what an import run did.
+type Report struct {
+	Repos []RepoReport
+	// Versions is the version index of a run with releases.
+	Versions []VersionIndexEntry
+	// Failures are the repos that failed to import in a run with KeepGoing.
+	Failures []RepoFailure
+	// BrokenLinks maps an imported dst path to its broken anchors and site
+	// links.
+	BrokenLinks map[string][]string
+}
+
+// RepoReport describes the import of one repo.
+type RepoReport struct {
+	Name   string
+	Branch string
+	// Version is the release the repo was imported for, if any.
+	Version string
+	// Commit is the SHA of the imported commit.
+	Commit string
+	// Files are the written dst paths, relative to the site root.
+	Files []string
+	// Assets are the written asset paths, relative to the site root.
+	Assets []string
+	// Warnings are the problems that did not stop the import, such as
+	// missing assets.
+	Warnings []string
+}
+
+// RepoFailure describes a repo that failed to import.
+type RepoFailure struct {
+	Name   string
+	Branch string
+	// Version is the release the repo was imported for, if any.
+	Version string
+	Err     *RepoError
+}
+
+// VersionIndexEntry is one item of the version index data file, which the
+// layouts can use to render a version switcher.
+type VersionIndexEntry struct {
+	Version  string            `json:"version"`
+	Path     string            `json:"path"`
+	Branches map[string]string `json:"branches"`
+}
+
+// Run imports every repo of cfg into cfg.SiteRoot, then checks the links of
+// the imported files. Broken links are returned as a *LinkError. With
+// cfg.KeepGoing, repos that fail are skipped and returned as an *ImportError
+// once the other repos are imported; their broken links are only reported in
+// the Report then.
+func (im *Importer) Run(ctx context.Context, cfg Config) (Report, error) {
+	report, err := im.run(ctx, cfg)
+	if err != nil {
+		return report, err
+	}
+
+	var files []string
+	for _, rr := range report.Repos {
+		files = append(files, rr.Files...)
+	}
+	report.BrokenLinks = im.verifyLinks(cfg.SiteRoot, files)
+	if len(report.Failures) > 0 {
+		return report, &ImportError{Failures: report.Failures}
+	}
+	if len(report.BrokenLinks) > 0 {
+		return report, &LinkError{Files: report.BrokenLinks}
+	}
+	return report, nil
+}
+
+func (im *Importer) run(ctx context.Context, cfg Config) (Report, error) {
+	var report Report
+	if err := cfg.Validate(); err != nil {
+		return report, err
+	}
+	if cfg.SiteRoot == "" || cfg.WorkDir == "" {
+		return report, &ConfigError{Err: fmt.Errorf("the site root and work directory must be set")}
+	}
+
+	//clean out temp directory
+	if err := im.FS.RemoveAll(cfg.WorkDir); err != nil {
+		return report, err
+	}
+	if err := im.FS.MkdirAll(cfg.WorkDir, 0750); err != nil {
+		return report, err
+	}
+
+	//without a list of releases, import each repo once from its own branch
+	if len(cfg.Releases) == 0 {
+		for _, repo := range cfg.Repos {
+			rr, err := im.importRepo(ctx, cfg, cfg.WorkDir, repo, repo.Branch, "")
+			if err != nil {
+				if !im.skipFailure(ctx, cfg, &report, rr, err) {
+					return report, err
+				}
+				continue
+			}
+			report.Repos = append(report.Repos, rr)
+		}
+		return report, nil
+	}
+
+	//otherwise import every repo once per release into a versioned directory
+	versionedRoot := path.Clean(cfg.VersionedRoot)
+	for _, rel := range cfg.Releases {
+		im.logf("\n\t\t\t*\t*\t*\n\nImporting release %q...\n", rel.Version)
+		workDir := filepath.Join(cfg.WorkDir, rel.Version)
+		if err := im.FS.MkdirAll(workDir, 0750); err != nil {
+			return report, err
+		}
+
+		versionDir := path.Join(versionedRoot, rel.Version)
+		entry := VersionIndexEntry{
+			Version:  rel.Version,
+			Path:     "/" + versionDir + "/",
+			Branches: make(map[string]string),
+		}
+		for _, repo := range cfg.Repos {
+			branch := repo.Branch
+			if override, ok := rel.Branches[repo.Name]; ok {
+				branch = override
+			}
+			rr, err := im.importRepo(ctx, cfg, workDir, repo, branch, versionDir)
+			rr.Version = rel.Version
+			if err != nil {
+				if !im.skipFailure(ctx, cfg, &report, rr, err) {
+					return report, err
+				}
+				continue
+			}
+			report.Repos = append(report.Repos, rr)
+			entry.Branches[repo.Name] = branch
+		}
+		report.Versions = append(report.Versions, entry)
+	}
+
+	//write the version index data file used by the version switcher
+	if cfg.VersionIndex != "" {
+		data, err := yaml.Marshal(report.Versions)
+		if err != nil {
+			return report, err
+		}
+		if err := im.writeSiteFile(cfg.SiteRoot, cfg.VersionIndex, data); err != nil {
+			return report, err
+		}
+		im.logf("Wrote version index %q\n", cfg.VersionIndex)
+	}
+	return report, nil
+}
+
+// skipFailure records the failed import of rr in report and reports whether
+// the run goes on with the next repo. Only a *RepoError of a run with
+// KeepGoing is skipped; an interrupted run always stops.
+func (im *Importer) skipFailure(ctx context.Context, cfg Config, report *Report, rr RepoReport, err error) bool {
+	rerr, ok := err.(*RepoError)
+	if !ok || !cfg.KeepGoing || ctx.Err() != nil {
+		return false
+	}
+	im.logf("\n%v\nSkipping repo %q, going on with the next one...\n", rerr, rr.Name)
+	report.Failures = append(report.Failures, RepoFailure{Name: rr.Name, Branch: rr.Branch, Version: rr.Version, Err: rerr})
+	return true
+}
+
+// importRepo clones repo at branch into workDir, runs its generate-command and
+// copies its files into the website. When versionDir is set, each `dst` is
+// written below versionDir instead.
+func (im *Importer) importRepo(ctx context.Context, cfg Config, workDir string, repo Repo, branch string, versionDir string) (RepoReport, error) {
+	rr := RepoReport{Name: repo.Name, Branch: branch}
+	if err := ctx.Err(); err != nil {
+		return rr, err
+	}
+	prefix, err := remotePrefix(repo.Remote, branch)
+	if err != nil {
+		return rr, &ConfigError{Err: err}
+	}
+
+	im.logf("\n\t\t\t*\t*\t*\n\nCloning repo %q at %q...\n", repo.Name, branch)
+	cloneDir := filepath.Join(workDir, repo.Name)
+	opts := CloneOptions{SparsePaths: repo.sparsePaths(cfg.AssetsDir != "")}
+	err = im.retry(ctx, fmt.Sprintf("Cloning repo %q", repo.Name), func() error {
+		// start over from an empty directory after a partial clone
+		if err := im.FS.RemoveAll(cloneDir); err != nil {
+			return err
+		}
+		if err := im.Git.Clone(ctx, repo.Remote, branch, cloneDir, opts); err != nil {
+			return err
+		}
+		var err error
+		rr.Commit, err = im.Git.Head(ctx, cloneDir)
+		return err
+	})
+	if err != nil {
+		return rr, &RepoError{Repo: repo.Name, Op: OpClone, Err: err}
+	}
+
+	//if generate-command is specified in the repo config,
+	//run the command for that repo, e.g. "hack/generate-docs.sh"
+	if repo.GenerateCommand != "" {
+		im.logf("Generating docs for repo %q with %q...\n\n", repo.Name, repo.GenerateCommand)
+		out := &prefixWriter{w: im.logWriter(), prefix: "generator output | "}
+		err := im.Exec.Run(ctx, cloneDir, out, repo.GenerateCommand)
+		out.Flush()
+		if err != nil {
+			return rr, &RepoError{Repo: repo.Name, Op: OpGenerate, Err: err}
+		}
+	}
+	if repo.Generator == GeneratorCobra {
+		written, err := im.generateCobra(ctx, cfg, cloneDir, repo, versionDir)
+		rr.Files = append(rr.Files, written...)
+		if err != nil {
+			return rr, &RepoError{Repo: repo.Name, Op: OpGenerate, Err: err}
+		}
+	}
+
+	err = im.copyFiles(ctx, cfg, cloneDir, repo, prefix, versionDir, &rr)
+	return rr, err
+}
+
+// copyFiles copies the files of repo from its checkout at cloneDir into the
+// website, and adds the written paths to rr.
+func (im *Importer) copyFiles(ctx context.Context, cfg Config, cloneDir string, repo Repo, prefix string, versionDir string, rr *RepoReport) error {
+	//copy and rename files from src -> dst specified in config
+	assets := &assetCopier{im: im, cfg: cfg, repo: repo, cloneDir: cloneDir, copied: map[string]bool{}}
+	for _, f := range repo.Files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		written, err := im.copyFile(cfg, cloneDir, repo, f, prefix, versionDir, assets)
+		rr.Files = append(rr.Files, written...)
+		if err != nil {
+			return &RepoError{Repo: repo.Name, Op: OpCopy, File: f.Src, Err: err}
+		}
+	}
+	for asset := range assets.copied {
+		rr.Assets = append(rr.Assets, asset)
+	}
+	sort.Strings(rr.Assets)
+	rr.Warnings = append(rr.Warnings, assets.warnings...)
+	return nil
+}
+
+// copyFile copies f from the clone to the website, keeping the title block of
+// the page it replaces. It returns the paths that were written.
+func (im *Importer) copyFile(cfg Config, cloneDir string, repo Repo, f File, prefix string, versionDir string, assets *assetCopier) ([]string, error) {
+	dst := path.Clean(f.Dst)
+	absDst := filepath.Join(cfg.SiteRoot, filepath.FromSlash(dst))
+	// Ignore the error if the old file is not found
+	old, _ := im.FS.ReadFile(absDst)
+	titleBlock := titleRegex.Find(old)
+
+	if versionDir != "" {
+		var err error
+		dst, err = versionedPath(dst, versionDir)
+		if err != nil {
+			return nil, err
+		}
+		absDst = filepath.Join(cfg.SiteRoot, filepath.FromSlash(dst))
+		// Prefer the title of an earlier import of this version, and fall
+		// back to the one of the unversioned page
+		if old, err := im.FS.ReadFile(absDst); err == nil {
+			if block := titleRegex.Find(old); block != nil {
+				titleBlock = block
+			}
+		}
+	}
+
+	if f.Mode == ModeKEPs {
+		return im.importKEPs(cfg, cloneDir, f, prefix, dst)
+	}
+	if f.Mode == ModeOpenAPI {
+		return im.importOpenAPI(cfg, cloneDir, f, dst, versionDir)
+	}
+
+	content, err := im.FS.ReadFile(filepath.Join(cloneDir, filepath.FromSlash(f.Src)))
+	if err != nil {
+		return nil, err
+	}
+	// Copy the assets first, so that their links are not made absolute
+	if cfg.AssetsDir != "" {
+		content, err = assets.rewrite(content, f.Src)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if f.Mode == ModeHTML {
+		return im.importHTML(cfg, repo, f, prefix, dst, titleBlock, content)
+	}
+	// Process content if necessary
+	if repo.GenAbsoluteLinks {
+		sitePrefix := ""
+		if cfg.AssetsDir != "" {
+			sitePrefix = "/" + path.Clean(cfg.AssetsDir) + "/"
+		}
+		content = processLinks(content, prefix, path.Dir(f.Src), sitePrefix)
+	}
+
+	if f.Mode == ModeChangelog {
+		return im.writeChangelog(cfg.SiteRoot, dst, titleBlock, content)
+	}
+	out := append(append([]byte{}, titleBlock...), content...)
+	if err := im.writeSiteFile(cfg.SiteRoot, dst, out); err != nil {
+		return nil, err
+	}
+	return []string{dst}, nil
+}
+
+func (im *Importer) logWriter() io.Writer {
+	if im.Log == nil {
+		return ioutil.Discard
+	}
+	return im.Log
+}
+
+func (im *Importer) logf(format string, args ...interface{}) {
+	fmt.Fprintf(im.logWriter(), format, args...)
+}
@@ -0,0 +1,281 @@
+package importer
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/website/update-imported-docs/marker"
+)
+
+// fakeGit "clones" by writing files into the clone directory.
+type fakeGit struct {
+	files map[string]string
+	err   error
+	// failures is how often the clone of a remote fails before it works.
+	failures map[string]int
+	clones   int
+}
+
+func (g *fakeGit) Clone(ctx context.Context, remote, branch, dir string, opts CloneOptions) error {
+	g.clones++
+	if g.err != nil {
+		return g.err
+	}
+	if g.failures[remote] > 0 {
+		g.failures[remote]--
+		// leave a partial clone behind
+		os.MkdirAll(dir, 0755)
+		ioutil.WriteFile(filepath.Join(dir, "partial"), nil, 0644)
+		return io.ErrUnexpectedEOF
+	}
+	// like git, create the clone even if it has no files
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, content := range g.files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *fakeGit) Head(ctx context.Context, dir string) (string, error) {
+	return "0123456789abcdef0123456789abcdef01234567", nil
+}
+
+type fakeRunner struct {
+	ran []string
+}
+
+func (r *fakeRunner) Run(ctx context.Context, dir string, stdout io.Writer, name string, args ...string) error {
+	r.ran = append(r.ran, name)
+	io.WriteString(stdout, "generated\n")
+	return nil
+}
+
+func TestRunCopiesFilesAndKeepsTitle(t *testing.T) {
+	root, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	site := filepath.Join(root, "site")
+	dst := filepath.Join(site, "docs", "imported", "guide.md")
+	os.MkdirAll(filepath.Dir(dst), 0755)
+	ioutil.WriteFile(dst, []byte("---\ntitle: Guide\n---\nold body\n"), 0644)
+
+	cfg, err := ParseConfig([]byte(`
+repos:
+- name: community
+  remote: https://github.com/kubernetes/community.git
+  branch: master
+  generate-command: hack/gen.sh
+  gen-absolute-links: true
+  files:
+  - src: guide/README.md
+    dst: docs/imported/guide.md
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SiteRoot = site
+	cfg.WorkDir = filepath.Join(root, "work")
+
+	runner := &fakeRunner{}
+	im := &Importer{
+		Git:  &fakeGit{files: map[string]string{"guide/README.md": "# Guide\nSee [the devel guide](../devel/README.md).\n"}},
+		Exec: runner,
+		FS:   OSFS{},
+	}
+	report, err := im.Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(runner.ran) != 1 || runner.ran[0] != "hack/gen.sh" {
+		t.Errorf("expected generate-command to run once, ran %v", runner.ran)
+	}
+	if len(report.Repos) != 1 || len(report.Repos[0].Files) != 1 || report.Repos[0].Files[0] != "docs/imported/guide.md" {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	got := readStamped(t, dst)
+	want := "---\ntitle: Guide\n---\nSee [the devel guide](https://github.com/kubernetes/community/tree/master/guide/../devel/README.md).\n"
+	if got != want {
+		t.Errorf("unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// readStamped reads an imported file, checks its generated-file marker and
+// returns the file without it.
+func readStamped(t *testing.T, name string) string {
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamped, unchanged := marker.Check(content)
+	if !stamped || !unchanged {
+		t.Errorf("%s: stamped %v, unchanged %v, want true and true", name, stamped, unchanged)
+	}
+	return marker.Regex.ReplaceAllString(string(content), "")
+}
+
+func TestRunWarnsAboutMissingAssets(t *testing.T) {
+	root, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	site := filepath.Join(root, "site")
+
+	cfg, err := ParseConfig([]byte(`
+assets-dir: images/imported
+repos:
+- name: community
+  remote: https://github.com/kubernetes/community.git
+  branch: master
+  gen-absolute-links: true
+  files:
+  - src: guide/README.md
+    dst: docs/imported/guide.md
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SiteRoot = site
+	cfg.WorkDir = filepath.Join(root, "work")
+
+	im := &Importer{
+		Git: &fakeGit{files: map[string]string{
+			"guide/README.md": "![found](found.png) ![missing](missing.png) <img src=\"../../outside.svg\">\n",
+			"guide/found.png": "png",
+		}},
+		Exec: &fakeRunner{},
+		FS:   OSFS{},
+	}
+	report, err := im.Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(report.Repos) != 1 || len(report.Repos[0].Assets) != 1 {
+		t.Fatalf("expected the found asset to be copied, got %+v", report)
+	}
+	warnings := report.Repos[0].Warnings
+	if len(warnings) != 2 || !strings.Contains(warnings[0], `"missing.png"`) || !strings.Contains(warnings[1], `"../../outside.svg"`) {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+
+	got := readStamped(t, filepath.Join(site, "docs", "imported", "guide.md"))
+	want := "![found](/" + report.Repos[0].Assets[0] + ") " +
+		"![missing](https://github.com/kubernetes/community/tree/master/guide/missing.png) " +
+		"<img src=\"../../outside.svg\">\n"
+	if got != want {
+		t.Errorf("unexpected content:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunKeepsKEPDirectories(t *testing.T) {
+	root, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	site := filepath.Join(root, "site")
+
+	cfg, err := ParseConfig([]byte(`
+repos:
+- name: community
+  remote: https://github.com/kubernetes/community.git
+  branch: master
+  files:
+  - src: keps/
+    dst: _data/keps.yml
+    mode: keps
+    stubs: docs/imported/community/keps
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SiteRoot = site
+	cfg.WorkDir = filepath.Join(root, "work")
+
+	im := &Importer{
+		Git: &fakeGit{files: map[string]string{
+			"keps/sig-node/0002-overhead.md": "---\ntitle: Pod Overhead\n---\n",
+			"keps/sig-apps/0002-overhead.md": "---\ntitle: Job Overhead\n---\n",
+		}},
+		Exec: &fakeRunner{},
+		FS:   OSFS{},
+	}
+	report, err := im.Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got := strings.Join(report.Repos[0].Files, " ")
+	want := "docs/imported/community/keps/sig-apps/0002-overhead.md docs/imported/community/keps/sig-node/0002-overhead.md _data/keps.yml"
+	if got != want {
+		t.Errorf("wrote %q, want %q", got, want)
+	}
+	for title, stub := range map[string]string{"Pod Overhead": "sig-node", "Job Overhead": "sig-apps"} {
+		content := readStamped(t, filepath.Join(site, "docs", "imported", "community", "keps", stub, "0002-overhead.md"))
+		if !strings.Contains(content, "title: "+title) {
+			t.Errorf("the %s stub is not of %q:\n%s", stub, title, content)
+		}
+	}
+}
+
+func TestRunReportsRepoError(t *testing.T) {
+	root, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cfg := Config{
+		SiteRoot: root,
+		WorkDir:  filepath.Join(root, "work"),
+		Repos: []Repo{{
+			Name:   "kubernetes",
+			Remote: "https://github.com/kubernetes/kubernetes.git",
+			Branch: "master",
+		}},
+	}
+	im := &Importer{Git: &fakeGit{err: io.ErrUnexpectedEOF}, Exec: &fakeRunner{}, FS: OSFS{}}
+	_, err = im.Run(context.Background(), cfg)
+	rerr, ok := err.(*RepoError)
+	if !ok {
+		t.Fatalf("expected a *RepoError, got %T: %v", err, err)
+	}
+	if rerr.Repo != "kubernetes" || rerr.Op != OpClone {
+		t.Errorf("unexpected error %+v", rerr)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	cases := map[string]string{
+		"no repos":          `repos: []`,
+		"invalid remote":    "repos:\n- name: a\n  remote: git@github.com:a/b.git\n  branch: master\n",
+		"no versioned-root": "releases:\n- version: v1.10\nrepos:\n- name: a\n  remote: https://github.com/a/b.git\n  branch: master\n",
+		"dst outside versioned-root": "versioned-root: docs/reference/generated\nreleases:\n- version: v1.10\n" +
+			"repos:\n- name: a\n  remote: https://github.com/a/b.git\n  branch: master\n  files:\n  - src: a.md\n    dst: docs/imported/a.md\n",
+		"import of cobra": "repos:\n- name: a\n  remote: https://github.com/a/b.git\n  branch: master\n  generator: cobra\n" +
+			"  commands:\n  - imports: [github.com/spf13/cobra, k8s.io/a/app]\n    command: app.New()\n    dst: docs/a\n",
+		"import named os": "repos:\n- name: a\n  remote: https://github.com/a/b.git\n  branch: master\n  generator: cobra\n" +
+			"  commands:\n  - imports: [os k8s.io/a/os]\n    command: os.New()\n    dst: docs/a\n",
+	}
+	for name, content := range cases {
+		_, err := ParseConfig([]byte(content))
+		if _, ok := err.(*ConfigError); !ok {
+			t.Errorf("%s: expected a *ConfigError, got %v", name, err)
+		}
+	}
+}
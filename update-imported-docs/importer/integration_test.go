@@ -0,0 +1,266 @@
+package importer_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/website/update-imported-docs/importer"
+)
+
+var update = flag.Bool("update", false, "update the golden files of the integration tests")
+
+const fixtures = "testdata/integration"
+
+var assetExtensions = map[string]bool{".png": true, ".svg": true}
+
+// fixtureGit clones the local fixture repo that stands in for a remote.
+type fixtureGit struct {
+	importer.Git
+	remotes map[string]string
+}
+
+func (g *fixtureGit) Clone(ctx context.Context, remote, branch, dir string, opts importer.CloneOptions) error {
+	local, ok := g.remotes[remote]
+	if !ok {
+		return fmt.Errorf("no fixture repo for %q", remote)
+	}
+	return g.Git.Clone(ctx, "file://"+local, branch, dir, opts)
+}
+
+// TestIntegration runs the importer for every config in the fixtures, with
+// throwaway git repos built from testdata/integration/repos, and compares the
+// resulting site with testdata/integration/golden/<config>.
+// Run `go test -run TestIntegration -update` to rewrite the golden files.
+func TestIntegration(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tmp, err := ioutil.TempDir("", "update-imported-docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	remotes := map[string]string{}
+	repos, err := ioutil.ReadDir(filepath.Join(fixtures, "repos"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range repos {
+		dir := filepath.Join(tmp, "remotes", r.Name())
+		if err := buildRepo(filepath.Join(fixtures, "repos", r.Name()), dir); err != nil {
+			t.Fatalf("building fixture repo %s: %v", r.Name(), err)
+		}
+		remotes[fmt.Sprintf("https://github.com/kubernetes/%s.git", r.Name())] = dir
+	}
+
+	configs, err := filepath.Glob(filepath.Join(fixtures, "configs", "*.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, configFile := range configs {
+		name := strings.TrimSuffix(filepath.Base(configFile), ".yml")
+		t.Run(name, func(t *testing.T) {
+			site := filepath.Join(tmp, "sites", name)
+			if err := copyTree(filepath.Join(fixtures, "site"), site); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := importer.LoadConfig(configFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.SiteRoot = site
+			cfg.WorkDir = filepath.Join(tmp, "work", name)
+
+			var log bytes.Buffer
+			im := importer.New(&log)
+			im.Git = &fixtureGit{Git: im.Git, remotes: remotes}
+			if _, err := im.Run(context.Background(), cfg); err != nil {
+				t.Fatalf("Run: %v\n%s", err, log.String())
+			}
+
+			// old git makes full clones
+			if len(cfg.Releases) == 0 && importer.SparseCheckoutSupported(context.Background(), importer.OSRunner{}) {
+				for _, repo := range cfg.Repos {
+					checkSparse(t, cfg, repo, filepath.Join(cfg.WorkDir, repo.Name))
+				}
+			}
+
+			golden := filepath.Join(fixtures, "golden", name)
+			if *update {
+				os.RemoveAll(golden)
+				if err := copyTree(site, golden); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			compareTrees(t, golden, site)
+		})
+	}
+}
+
+// checkSparse checks that the clone of a repo without a generate-command
+// only contains the files that are imported, and maybe assets.
+func checkSparse(t *testing.T, cfg importer.Config, repo importer.Repo, cloneDir string) {
+	if repo.GenerateCommand != "" || repo.FullClone != nil {
+		return
+	}
+	srcs := map[string]bool{}
+	for _, f := range repo.Files {
+		srcs[f.Src] = true
+	}
+	files, err := listFiles(cloneDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range files {
+		if cfg.AssetsDir != "" && assetExtensions[path.Ext(name)] {
+			continue
+		}
+		if !strings.HasPrefix(name, ".git/") && !srcs[name] && !srcs[path.Dir(name)] && !srcs[path.Dir(path.Dir(name))] {
+			t.Errorf("%s: %s should not be checked out", repo.Name, name)
+		}
+	}
+}
+
+// buildRepo creates a git repo in dir with one branch per subdirectory of
+// src. Every branch starts from master.
+func buildRepo(src, dir string) error {
+	branches, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	git := func(args ...string) error {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=fixture", "GIT_AUTHOR_EMAIL=fixture@example.com",
+			"GIT_COMMITTER_NAME=fixture", "GIT_COMMITTER_EMAIL=fixture@example.com",
+			"GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := git("init", "-q"); err != nil {
+		return err
+	}
+	if err := git("checkout", "-q", "-b", "master"); err != nil {
+		return err
+	}
+	// serve partial clones, like GitHub does
+	if err := git("config", "uploadpack.allowFilter", "true"); err != nil {
+		return err
+	}
+	// master first, so that the other branches can start from it
+	sort.Slice(branches, func(i, j int) bool {
+		return branches[i].Name() == "master" && branches[j].Name() != "master"
+	})
+	for _, b := range branches {
+		if b.Name() != "master" {
+			if err := git("checkout", "-q", "-b", b.Name(), "master"); err != nil {
+				return err
+			}
+			if err := git("rm", "-q", "-r", "."); err != nil {
+				return err
+			}
+		}
+		if err := copyTree(filepath.Join(src, b.Name()), dir); err != nil {
+			return err
+		}
+		if err := git("add", "-A"); err != nil {
+			return err
+		}
+		if err := git("commit", "-q", "-m", "fixture "+b.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyTree copies the files below src into dst. Shell scripts are made
+// executable.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if strings.HasSuffix(path, ".sh") {
+			mode = 0755
+		}
+		return ioutil.WriteFile(target, data, mode)
+	})
+}
+
+// compareTrees checks that got contains exactly the files of want.
+func compareTrees(t *testing.T, want, got string) {
+	wantFiles, err := listFiles(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotFiles, err := listFiles(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, wantContent := range wantFiles {
+		gotContent, ok := gotFiles[name]
+		if !ok {
+			t.Errorf("%s: missing", name)
+			continue
+		}
+		if gotContent != wantContent {
+			t.Errorf("%s: got\n%s\nwant\n%s", name, gotContent, wantContent)
+		}
+	}
+	for name := range gotFiles {
+		if _, ok := wantFiles[name]; !ok {
+			t.Errorf("%s: unexpected file", name)
+		}
+	}
+}
+
+func listFiles(root string) (map[string]string, error) {
+	files := map[string]string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// ModeKEPs reads the metadata of every KEP below a directory into a data
+// file, see File.Mode.
+const ModeKEPs = "keps"
+
+// KEP is the metadata of a Kubernetes Enhancement Proposal, as written to the
+// data file.
+type KEP struct {
+	Number            string   `json:"number"`
+	Title             string   `json:"title"`
+	Authors           []string `json:"authors"`
+	OwningSIG         string   `json:"owning-sig"`
+	ParticipatingSIGs []string `json:"participating-sigs,omitempty"`
+	Status            string   `json:"status"`
+	CreationDate      string   `json:"creation-date,omitempty"`
+	LastUpdated       string   `json:"last-updated,omitempty"`
+	// URL is where the KEP is on GitHub.
+	URL string `json:"url"`
+	// Page is the path of the stub page on the site, if any.
+	Page string `json:"page,omitempty"`
+}
+
+// kepMetadata is the front matter of a KEP file. People are either names or
+// maps with a name.
+type kepMetadata struct {
+	Number            interface{}   `json:"kep-number"`
+	Title             string        `json:"title"`
+	Authors           []interface{} `json:"authors"`
+	OwningSIG         string        `json:"owning-sig"`
+	ParticipatingSIGs []string      `json:"participating-sigs"`
+	Status            string        `json:"status"`
+	CreationDate      string        `json:"creation-date"`
+	LastUpdated       string        `json:"last-updated"`
+}
+
+// parseKEP returns the metadata of a KEP file, or nil if it has none.
+func parseKEP(content []byte) (*KEP, error) {
+	if !bytes.HasPrefix(content, []byte("---\n")) {
+		return nil, nil
+	}
+	end := bytes.Index(content[4:], []byte("\n---\n"))
+	if end < 0 {
+		return nil, nil
+	}
+	var meta kepMetadata
+	if err := yaml.Unmarshal(content[4:4+end+1], &meta); err != nil {
+		return nil, err
+	}
+	if meta.Title == "" {
+		return nil, nil
+	}
+
+	kep := &KEP{
+		Title:             meta.Title,
+		OwningSIG:         meta.OwningSIG,
+		ParticipatingSIGs: meta.ParticipatingSIGs,
+		Status:            meta.Status,
+		CreationDate:      meta.CreationDate,
+		LastUpdated:       meta.LastUpdated,
+	}
+	if meta.Number != nil {
+		kep.Number = fmt.Sprint(meta.Number)
+	}
+	for _, a := range meta.Authors {
+		switch a := a.(type) {
+		case string:
+			kep.Authors = append(kep.Authors, a)
+		case map[string]interface{}:
+			if name, ok := a["name"].(string); ok {
+				kep.Authors = append(kep.Authors, name)
+			}
+		}
+	}
+	return kep, nil
+}
+
+// importKEPs reads every KEP below the directory f.Src of the clone, writes
+// their metadata to dst and, if f.Stubs is set, a stub page per KEP. It
+// returns the written paths, relative to the site root.
+func (im *Importer) importKEPs(cfg Config, cloneDir string, f File, prefix string, dst string) ([]string, error) {
+	src := path.Clean(f.Src)
+	var keps []KEP
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		infos, err := im.FS.ReadDir(filepath.Join(cloneDir, filepath.FromSlash(dir)))
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			name := path.Join(dir, info.Name())
+			if info.IsDir() {
+				if err := walk(name); err != nil {
+					return err
+				}
+				continue
+			}
+			// 0000 is the number of the KEP template
+			if path.Ext(name) != ".md" || strings.HasPrefix(info.Name(), "0000-") {
+				continue
+			}
+			content, err := im.FS.ReadFile(filepath.Join(cloneDir, filepath.FromSlash(name)))
+			if err != nil {
+				return err
+			}
+			kep, err := parseKEP(content)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			if kep == nil {
+				im.logf("Skipping %q, it has no KEP metadata\n", name)
+				continue
+			}
+			kep.URL = prefix + "/" + name
+			if f.Stubs != "" {
+				// Keep the directories below src, which may reuse file
+				// names, e.g. keps/sig-node/0002-x.md and keps/sig-apps/0002-x.md
+				rel := strings.TrimPrefix(name, src+"/")
+				kep.Page = "/" + path.Join(f.Stubs, strings.TrimSuffix(rel, ".md")) + "/"
+			}
+			keps = append(keps, *kep)
+		}
+		return nil
+	}
+	if err := walk(src); err != nil {
+		return nil, err
+	}
+	sort.Slice(keps, func(i, j int) bool { return keps[i].URL < keps[j].URL })
+
+	var written []string
+	for _, kep := range keps {
+		if kep.Page == "" {
+			continue
+		}
+		page, err := kepStub(kep)
+		if err != nil {
+			return written, err
+		}
+		stub := strings.TrimSuffix(strings.TrimPrefix(kep.Page, "/"), "/") + ".md"
+		if err := im.writeSiteFile(cfg.SiteRoot, stub, page); err != nil {
+			return written, err
+		}
+		written = append(written, stub)
+	}
+
+	data, err := yaml.Marshal(keps)
+	if err != nil {
+		return written, err
+	}
+	if err := im.writeSiteFile(cfg.SiteRoot, dst, data); err != nil {
+		return written, err
+	}
+	im.logf("Wrote the metadata of %d KEPs to %q\n", len(keps), dst)
+	return append(written, dst), nil
+}
+
+// kepStub returns a page that summarizes kep and links to it on GitHub.
+func kepStub(kep KEP) ([]byte, error) {
+	titleLine, err := yaml.Marshal(map[string]string{"title": kep.Title})
+	if err != nil {
+		return nil, err
+	}
+	frontMatter, err := yaml.Marshal(map[string]interface{}{"kep": kep})
+	if err != nil {
+		return nil, err
+	}
+	var page bytes.Buffer
+	page.WriteString("---\n")
+	page.Write(titleLine)
+	page.Write(frontMatter)
+	page.WriteString("---\n\n")
+	fmt.Fprintf(&page, "KEP %s is owned by %s and is %s.\n\n", kep.Number, kep.OwningSIG, kep.Status)
+	if len(kep.Authors) > 0 {
+		fmt.Fprintf(&page, "Authors: %s\n\n", strings.Join(kep.Authors, ", "))
+	}
+	fmt.Fprintf(&page, "[Read the KEP on GitHub](%s).\n", kep.URL)
+	return page.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Match the content between 2 `---`
+// It mostly have something like:
+// ---
+// title: ***
+// notile: ***
+// ---
+var titleRegex = regexp.MustCompile("^---\ntitle:(.*\n)*?---\n")
+
+// To extract repo path prefix from `remote`
+var remoteGitRegex = regexp.MustCompile("(https://.*)\\.git$")
+
+// To catch anything of the form [text](url)
+var linkRegex = regexp.MustCompile("(\\[.+?\\])\\(([^\\s\\)]+)\\)")
+
+// Regexes to skip
+var (
+	absURLRegex = regexp.MustCompile("https*://")
+	mailRegex   = regexp.MustCompile("mailto:")
+)
+
+var h1Regex = regexp.MustCompile("^(# .*)?\n")
+
+// remotePrefix returns the URL that links relative to the root of the repo
+// are resolved against, e.g. https://github.com/kubernetes/community/tree/master
+func remotePrefix(remote, branch string) (string, error) {
+	match := remoteGitRegex.FindStringSubmatch(remote)
+	if match == nil {
+		return "", fmt.Errorf("invalid remote path %q. Schema should look like: https://<url>.git", remote)
+	}
+	return fmt.Sprintf("%s/tree/%s", match[1], branch), nil
+}
+
+// ProcessLinks turns the relative links of a file at subPath in the repo
+// into absolute links below remotePrefix, and drops a leading H1 heading.
+func ProcessLinks(content []byte, remotePrefix string, subPath string) []byte {
+	return processLinks(content, remotePrefix, subPath, "")
+}
+
+// processLinks is ProcessLinks, but leaves links to sitePrefix alone, e.g.
+// to the imported assets.
+func processLinks(content []byte, remotePrefix string, subPath string, sitePrefix string) []byte {
+	processedContent := linkRegex.ReplaceAllFunc(content, func(b []byte) []byte {
+		if absURLRegex.Match(b) || mailRegex.Match(b) {
+			return b // no processing needed
+		}
+		match := linkRegex.FindAllStringSubmatch(string(b), -1)
+		url := rewriteLink(match[0][2], remotePrefix, subPath, sitePrefix)
+		return []byte(fmt.Sprintf("%s(%s)", match[0][1], url))
+	})
+
+	return h1Regex.ReplaceAll(processedContent, []byte(""))
+}
+
+// rewriteLink turns a relative url of a file at subPath in the repo into an
+// absolute link below remotePrefix. Links on the current page or below
+// sitePrefix are left alone.
+func rewriteLink(url string, remotePrefix string, subPath string, sitePrefix string) string {
+	if url == "" || absURLRegex.MatchString(url) || mailRegex.MatchString(url) {
+		return url
+	}
+	if sitePrefix != "" && strings.HasPrefix(url, sitePrefix) { // link on the site
+		return url
+	} else if url[0] == '#' { // link on current page
+		return url
+	} else if url[0] == '/' { // link at root of repo
+		return fmt.Sprintf("%s/%s", remotePrefix, url[1:])
+	}
+	// link relative to current page
+	return fmt.Sprintf("%s/%s/%s", remotePrefix, subPath, url)
+}
+
+// versionedPath moves dst below versionDir. The parent of versionDir is the
+// versioned root, and dst must be inside it, e.g. with a versionDir of
+// "docs/reference/generated/v1.10", "docs/reference/generated/kubelet.md"
+// becomes "docs/reference/generated/v1.10/kubelet.md".
+func versionedPath(dst string, versionDir string) (string, error) {
+	root := path.Dir(path.Clean(versionDir))
+	rel := strings.TrimPrefix(path.Clean(dst), root+"/")
+	if rel == path.Clean(dst) {
+		return "", fmt.Errorf("dst %q is not inside the versioned root %q", dst, root)
+	}
+	return path.Join(versionDir, rel), nil
+}
@@ -0,0 +1,447 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// ModeOpenAPI writes a reference page per definition of an OpenAPI (Swagger
+// 2.0) spec, see File.Mode.
+const ModeOpenAPI = "openapi"
+
+// To catch the version segment of a definition name, e.g. "v1beta1" in
+// "io.k8s.api.extensions.v1beta1.Deployment"
+var apiVersionRegex = regexp.MustCompile(`^v\d+((alpha|beta)\d+)?$`)
+
+// openAPISpec is the part of an OpenAPI spec the pages are generated from.
+type openAPISpec struct {
+	Info struct {
+		Title string `json:"title"`
+	} `json:"info"`
+	// Paths maps a path to its operations by method, and to the keys shared
+	// by them, like "parameters".
+	Paths       map[string]map[string]interface{} `json:"paths"`
+	Definitions map[string]openAPISchema          `json:"definitions"`
+}
+
+type openAPISchema struct {
+	Description          string                   `json:"description"`
+	Type                 string                   `json:"type"`
+	Format               string                   `json:"format"`
+	Ref                  string                   `json:"$ref"`
+	Items                *openAPISchema           `json:"items"`
+	AdditionalProperties *openAPISchema           `json:"additionalProperties"`
+	Properties           map[string]openAPISchema `json:"properties"`
+	Required             []string                 `json:"required"`
+	GVK                  []groupVersionKind       `json:"x-kubernetes-group-version-kind"`
+}
+
+type openAPIOperation struct {
+	Description string            `json:"description"`
+	OperationID string            `json:"operationId"`
+	Action      string            `json:"x-kubernetes-action"`
+	GVK         *groupVersionKind `json:"x-kubernetes-group-version-kind"`
+}
+
+type groupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// apiPage is the page of one definition.
+type apiPage struct {
+	Name  string
+	Group string
+	// Version is empty for definitions outside of an API version, e.g.
+	// resource.Quantity.
+	Version  string
+	Kind     string
+	Resource bool
+	Schema   openAPISchema
+	// Page is the path of the page, relative to the site root.
+	Page   string
+	UsedBy []*apiPage
+	Ops    []apiOperation
+}
+
+type apiOperation struct {
+	Action, Method, Path, Description string
+}
+
+// apiVersion is the group version, as in the apiVersion field of objects.
+func (p *apiPage) apiVersion() string {
+	switch {
+	case p.Version == "":
+		return p.Group
+	case p.Group == "core":
+		return p.Version
+	}
+	return p.Group + "/" + p.Version
+}
+
+func (p *apiPage) sitePath() string {
+	return "/" + strings.TrimSuffix(p.Page, ".md") + "/"
+}
+
+// newAPIPage returns the page of the definition name below dir. Its group,
+// version and kind come from the x-kubernetes-group-version-kind extension,
+// or else from the name.
+func newAPIPage(name string, schema openAPISchema, dir string) *apiPage {
+	p := &apiPage{Name: name, Schema: schema}
+	if len(schema.GVK) > 0 {
+		gvk := schema.GVK[0]
+		p.Group, p.Version, p.Kind, p.Resource = gvk.Group, gvk.Version, gvk.Kind, true
+	} else {
+		segments := strings.Split(name, ".")
+		p.Kind = segments[len(segments)-1]
+		if len(segments) > 1 {
+			p.Group = segments[len(segments)-2]
+		}
+		for i := len(segments) - 2; i >= 0; i-- {
+			if apiVersionRegex.MatchString(segments[i]) {
+				p.Version = segments[i]
+				p.Group = ""
+				if i > 0 {
+					p.Group = segments[i-1]
+				}
+				break
+			}
+		}
+	}
+	if p.Group == "" || p.Group == "api" {
+		p.Group = "core"
+	}
+	p.Page = path.Join(dir, p.Group, p.Version, strings.ToLower(p.Kind)+".md")
+	return p
+}
+
+// importOpenAPI writes a page per definition of the OpenAPI spec f.Src below
+// the directory dst, an index of them at dst/index.md and, if f.TOC is set,
+// a table of contents, named after versionDir if it is set. It returns the
+// written paths, relative to the site root.
+func (im *Importer) importOpenAPI(cfg Config, cloneDir string, f File, dst string, versionDir string) ([]string, error) {
+	content, err := im.FS.ReadFile(filepath.Join(cloneDir, filepath.FromSlash(f.Src)))
+	if err != nil {
+		return nil, err
+	}
+	var spec openAPISpec
+	if err := yaml.Unmarshal(content, &spec); err != nil {
+		return nil, err
+	}
+	if len(spec.Definitions) == 0 {
+		return nil, fmt.Errorf("no definitions found")
+	}
+
+	var names []string
+	for name := range spec.Definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pages := map[string]*apiPage{}
+	byPath := map[string]*apiPage{}
+	var all []*apiPage
+	for _, name := range names {
+		p := newAPIPage(name, spec.Definitions[name], dst)
+		if other, ok := byPath[p.Page]; ok {
+			return nil, fmt.Errorf("definitions %q and %q would both be written to %q", other.Name, name, p.Page)
+		}
+		pages[name], byPath[p.Page] = p, p
+		all = append(all, p)
+	}
+	for _, p := range all {
+		for _, ref := range schemaRefs(p.Schema) {
+			if target, ok := pages[ref]; ok && target != p && !containsPage(target.UsedBy, p) {
+				target.UsedBy = append(target.UsedBy, p)
+			}
+		}
+	}
+	if err := addOperations(spec, all); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, p := range all {
+		page, err := apiReferencePage(p, pages)
+		if err != nil {
+			return written, err
+		}
+		if err := im.writeSiteFile(cfg.SiteRoot, p.Page, page); err != nil {
+			return written, err
+		}
+		written = append(written, p.Page)
+	}
+
+	// keep the title of the index, as for copied files
+	index := path.Join(dst, "index.md")
+	old, _ := im.FS.ReadFile(filepath.Join(cfg.SiteRoot, filepath.FromSlash(index)))
+	titleBlock := titleRegex.Find(old)
+	if titleBlock == nil {
+		title := strings.TrimSpace(spec.Info.Title + " Reference")
+		titleLine, err := yaml.Marshal(map[string]string{"title": title})
+		if err != nil {
+			return written, err
+		}
+		titleBlock = append(append([]byte("---\n"), titleLine...), "---\n"...)
+	}
+	if err := im.writeSiteFile(cfg.SiteRoot, index, apiIndexPage(titleBlock, all)); err != nil {
+		return written, err
+	}
+	written = append(written, index)
+
+	if toc := versionedTOC(f.TOC, versionDir); toc != "" {
+		data, err := yaml.Marshal(map[string]interface{}{
+			"bigheader": strings.TrimSpace(spec.Info.Title + " Reference"),
+			"toc":       apiTOC(index, all),
+		})
+		if err != nil {
+			return written, err
+		}
+		if err := im.writeSiteFile(cfg.SiteRoot, toc, data); err != nil {
+			return written, err
+		}
+		written = append(written, toc)
+	}
+	im.logf("Wrote %d API reference pages to %q\n", len(all), dst)
+	return written, nil
+}
+
+// addOperations adds the operations of the spec to the pages of the kinds
+// they act on.
+func addOperations(spec openAPISpec, all []*apiPage) error {
+	kinds := map[groupVersionKind]*apiPage{}
+	for _, p := range all {
+		if p.Resource {
+			gvk := p.Schema.GVK[0]
+			if _, ok := kinds[gvk]; !ok {
+				kinds[gvk] = p
+			}
+		}
+	}
+	var paths []string
+	for p := range spec.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		for _, method := range []string{"get", "put", "post", "patch", "delete"} {
+			raw, ok := spec.Paths[p][method]
+			if !ok {
+				continue
+			}
+			data, err := yaml.Marshal(raw)
+			if err != nil {
+				return err
+			}
+			var op openAPIOperation
+			if err := yaml.Unmarshal(data, &op); err != nil {
+				return fmt.Errorf("%s %s: %v", strings.ToUpper(method), p, err)
+			}
+			if op.GVK == nil {
+				continue
+			}
+			page, ok := kinds[*op.GVK]
+			if !ok {
+				continue
+			}
+			action := op.Action
+			if action == "" {
+				action = op.OperationID
+			}
+			page.Ops = append(page.Ops, apiOperation{
+				Action:      action,
+				Method:      strings.ToUpper(method),
+				Path:        p,
+				Description: op.Description,
+			})
+		}
+	}
+	return nil
+}
+
+// schemaRefs returns the names of the definitions s refers to.
+func schemaRefs(s openAPISchema) []string {
+	var refs []string
+	if s.Ref != "" {
+		refs = append(refs, strings.TrimPrefix(s.Ref, "#/definitions/"))
+	}
+	if s.Items != nil {
+		refs = append(refs, schemaRefs(*s.Items)...)
+	}
+	if s.AdditionalProperties != nil {
+		refs = append(refs, schemaRefs(*s.AdditionalProperties)...)
+	}
+	for _, name := range sortedProperties(s) {
+		refs = append(refs, schemaRefs(s.Properties[name])...)
+	}
+	return refs
+}
+
+func containsPage(pages []*apiPage, p *apiPage) bool {
+	for _, q := range pages {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedProperties(s openAPISchema) []string {
+	var names []string
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaType describes the type of s in a table cell, with links to the pages
+// of the definitions it refers to.
+func schemaType(s openAPISchema, pages map[string]*apiPage) string {
+	switch {
+	case s.Ref != "":
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		if p, ok := pages[name]; ok {
+			return fmt.Sprintf("[%s](%s)", p.Kind, p.sitePath())
+		}
+		return name
+	case s.Type == "array" && s.Items != nil:
+		return "array of " + schemaType(*s.Items, pages)
+	case s.AdditionalProperties != nil:
+		return "map of string to " + schemaType(*s.AdditionalProperties, pages)
+	case s.Type == "":
+		return "object"
+	case s.Format != "":
+		return fmt.Sprintf("%s (%s)", s.Type, s.Format)
+	}
+	return s.Type
+}
+
+// tableCell makes text fit in a cell of a Markdown table.
+func tableCell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.Replace(text, "|", `\|`, -1)
+}
+
+// apiReferencePage renders the page of p.
+func apiReferencePage(p *apiPage, pages map[string]*apiPage) ([]byte, error) {
+	titleLine, err := yaml.Marshal(map[string]string{"title": strings.TrimSpace(p.Kind + " " + p.apiVersion())})
+	if err != nil {
+		return nil, err
+	}
+	frontMatter, err := yaml.Marshal(map[string]interface{}{
+		"api_metadata": map[string]string{"group": p.Group, "version": p.Version, "kind": p.Kind},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString("---\n")
+	b.Write(titleLine)
+	b.Write(frontMatter)
+	b.WriteString("---\n\n")
+	// descriptions may contain Go templates, which are not Liquid
+	b.WriteString("{% raw %}\n")
+	if p.Resource {
+		fmt.Fprintf(&b, "`apiVersion: %s`\n\n", p.apiVersion())
+	}
+	if p.Schema.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", strings.TrimSpace(p.Schema.Description))
+	}
+
+	if len(p.Schema.Properties) > 0 {
+		required := map[string]bool{}
+		for _, name := range p.Schema.Required {
+			required[name] = true
+		}
+		b.WriteString("## Fields\n\n| Field | Type | Description |\n| --- | --- | --- |\n")
+		for _, name := range sortedProperties(p.Schema) {
+			prop := p.Schema.Properties[name]
+			description := tableCell(prop.Description)
+			if required[name] {
+				description = strings.TrimSpace("**Required.** " + description)
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", name, schemaType(prop, pages), description)
+		}
+		b.WriteString("\n")
+	} else if p.Schema.Type != "" || p.Schema.Ref != "" {
+		fmt.Fprintf(&b, "Type: %s\n\n", schemaType(p.Schema, pages))
+	}
+
+	if len(p.Ops) > 0 {
+		b.WriteString("## Operations\n\n| Operation | HTTP request | Description |\n| --- | --- | --- |\n")
+		for _, op := range p.Ops {
+			fmt.Fprintf(&b, "| %s | `%s %s` | %s |\n", op.Action, op.Method, op.Path, tableCell(op.Description))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(p.UsedBy) > 0 {
+		b.WriteString("## Used by\n\n")
+		for _, u := range p.UsedBy {
+			fmt.Fprintf(&b, "* [%s %s](%s)\n", u.Kind, u.apiVersion(), u.sitePath())
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("{% endraw %}\n")
+	return b.Bytes(), nil
+}
+
+// apiGroupVersions returns the pages grouped by API version, in order.
+func apiGroupVersions(all []*apiPage) (versions []string, byVersion map[string][]*apiPage) {
+	byVersion = map[string][]*apiPage{}
+	for _, p := range all {
+		v := p.apiVersion()
+		if _, ok := byVersion[v]; !ok {
+			versions = append(versions, v)
+		}
+		byVersion[v] = append(byVersion[v], p)
+	}
+	sort.Strings(versions)
+	for _, v := range versions {
+		pages := byVersion[v]
+		sort.Slice(pages, func(i, j int) bool { return pages[i].Kind < pages[j].Kind })
+	}
+	return versions, byVersion
+}
+
+// apiIndexPage renders the index of the pages, with the resources of each
+// API version first.
+func apiIndexPage(titleBlock []byte, all []*apiPage) []byte {
+	var b bytes.Buffer
+	b.Write(titleBlock)
+	versions, byVersion := apiGroupVersions(all)
+	for _, v := range versions {
+		fmt.Fprintf(&b, "\n## %s\n\n", v)
+		for _, resources := range []bool{true, false} {
+			for _, p := range byVersion[v] {
+				if p.Resource == resources {
+					fmt.Fprintf(&b, "* [%s](%s)\n", p.Kind, p.sitePath())
+				}
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+// apiTOC returns the table of contents of the pages, with a section per API
+// version.
+func apiTOC(index string, all []*apiPage) []interface{} {
+	toc := []interface{}{index}
+	versions, byVersion := apiGroupVersions(all)
+	for _, v := range versions {
+		var section []interface{}
+		for _, p := range byVersion[v] {
+			section = append(section, p.Page)
+		}
+		toc = append(toc, map[string]interface{}{"title": v, "section": section})
+	}
+	return toc
+}
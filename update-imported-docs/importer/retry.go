@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy configures how failed git operations are retried.
+type RetryPolicy struct {
+	// Attempts is the number of tries, including the first one. Zero means
+	// a single try.
+	Attempts int
+	// Backoff is the wait before the first retry. It doubles for every
+	// further retry.
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy of New.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 3, Backoff: 5 * time.Second}
+
+// retry calls op until it succeeds, the attempts of im.Retry are used up or
+// ctx is done, and returns the last error. what names op in the log.
+func (im *Importer) retry(ctx context.Context, what string, op func() error) error {
+	backoff := im.Retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= im.Retry.Attempts || ctx.Err() != nil {
+			return err
+		}
+		im.logf("%s failed (attempt %d of %d): %v\nRetrying in %v...\n", what, attempt, im.Retry.Attempts, err, backoff)
+		if err := im.wait(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// wait sleeps for d, or until ctx is done.
+func (im *Importer) wait(ctx context.Context, d time.Duration) error {
+	if im.sleep != nil {
+		return im.sleep(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,142 @@
+package importer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunRetriesClone(t *testing.T) {
+	root, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cfg, err := ParseConfig([]byte(`
+repos:
+- name: community
+  remote: https://github.com/kubernetes/community.git
+  branch: master
+  files:
+  - src: README.md
+    dst: docs/imported/community.md
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SiteRoot = filepath.Join(root, "site")
+	cfg.WorkDir = filepath.Join(root, "work")
+
+	git := &fakeGit{
+		files:    map[string]string{"README.md": "# Community\n"},
+		failures: map[string]int{"https://github.com/kubernetes/community.git": 2},
+	}
+	var waits []time.Duration
+	im := &Importer{Git: git, Exec: &fakeRunner{}, FS: OSFS{}, Retry: RetryPolicy{Attempts: 3, Backoff: time.Second}}
+	im.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	if _, err := im.Run(context.Background(), cfg); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if git.clones != 3 {
+		t.Errorf("expected 3 clones, got %d", git.clones)
+	}
+	if len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Errorf("unexpected backoff %v", waits)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.WorkDir, "community", "partial")); !os.IsNotExist(err) {
+		t.Errorf("expected the partial clone to be removed before retrying, got %v", err)
+	}
+
+	// the attempts are used up
+	git.failures["https://github.com/kubernetes/community.git"] = 3
+	git.clones = 0
+	_, err = im.Run(context.Background(), cfg)
+	if rerr, ok := err.(*RepoError); !ok || rerr.Op != OpClone {
+		t.Fatalf("expected a clone *RepoError, got %T: %v", err, err)
+	}
+	if git.clones != 3 {
+		t.Errorf("expected 3 clones, got %d", git.clones)
+	}
+}
+
+func TestRunKeepGoing(t *testing.T) {
+	root, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	cfg, err := ParseConfig([]byte(`
+versioned-root: docs/reference/generated
+releases:
+- version: v1.10
+- version: v1.9
+  branches:
+    kubernetes: release-1.9
+repos:
+- name: kubernetes
+  remote: https://github.com/kubernetes/kubernetes.git
+  branch: release-1.10
+  files:
+  - src: README.md
+    dst: docs/reference/generated/kubernetes.md
+- name: community
+  remote: https://github.com/kubernetes/community.git
+  branch: master
+  files:
+  - src: README.md
+    dst: docs/reference/generated/community.md
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SiteRoot = filepath.Join(root, "site")
+	cfg.WorkDir = filepath.Join(root, "work")
+	cfg.KeepGoing = true
+
+	git := &fakeGit{
+		files:    map[string]string{"README.md": "# Readme\n"},
+		failures: map[string]int{"https://github.com/kubernetes/kubernetes.git": 100},
+	}
+	im := &Importer{Git: git, Exec: &fakeRunner{}, FS: OSFS{}, Retry: RetryPolicy{Attempts: 2}}
+	report, err := im.Run(context.Background(), cfg)
+	ierr, ok := err.(*ImportError)
+	if !ok {
+		t.Fatalf("expected an *ImportError, got %T: %v", err, err)
+	}
+
+	var imported []string
+	for _, rr := range report.Repos {
+		imported = append(imported, rr.Name+"@"+rr.Version)
+	}
+	if got := strings.Join(imported, " "); got != "community@v1.10 community@v1.9" {
+		t.Errorf("unexpected imported repos %q", got)
+	}
+	for _, p := range []string{"docs/reference/generated/v1.10/community.md", "docs/reference/generated/v1.9/community.md"} {
+		if _, err := os.Stat(filepath.Join(cfg.SiteRoot, p)); err != nil {
+			t.Errorf("expected %s to be imported: %v", p, err)
+		}
+	}
+
+	if len(ierr.Failures) != 2 {
+		t.Fatalf("expected 2 failures, got %+v", ierr.Failures)
+	}
+	f := ierr.Failures[1]
+	if f.Name != "kubernetes" || f.Branch != "release-1.9" || f.Version != "v1.9" || f.Err.Op != OpClone {
+		t.Errorf("unexpected failure %+v", f)
+	}
+	want := "2 repos failed to import:\n" +
+		"kubernetes at \"release-1.10\" for release \"v1.10\":\n\terror when cloning repo \"kubernetes\": unexpected EOF\n" +
+		"kubernetes at \"release-1.9\" for release \"v1.9\":\n\terror when cloning repo \"kubernetes\": unexpected EOF"
+	if got := ierr.Error(); got != want {
+		t.Errorf("unexpected message:\n%s\nwant:\n%s", got, want)
+	}
+}
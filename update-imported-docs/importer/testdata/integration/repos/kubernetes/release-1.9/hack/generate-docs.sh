@@ -0,0 +1,8 @@
+#!/bin/sh
+# Fake generator: writes the reference docs for the version in VERSION.
+set -e
+version=$(cat VERSION)
+mkdir -p docs/admin docs/user-guide/kubectl
+printf '## kubelet\n\nReference for kubelet %s.\n' "$version" > docs/admin/kubelet.md
+printf '## kubectl\n\nReference for kubectl %s.\n' "$version" > docs/user-guide/kubectl/kubectl.md
+echo "generated docs for $version"
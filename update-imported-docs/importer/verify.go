@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/website/update-imported-docs/marker"
+)
+
+// To catch ATX headings, e.g. "## Title {#id}"
+var atxHeadingRegex = regexp.MustCompile(`^#{1,6}[ \t]+(.*?)[ \t#]*$`)
+
+// To catch setext heading underlines
+var setextRegex = regexp.MustCompile(`^(=+|-+)[ \t]*$`)
+
+// To catch a kramdown header ID, e.g. "{#id}"
+var explicitIDRegex = regexp.MustCompile(`[ \t]*\{#([^}\s]+)\}$`)
+
+// To catch HTML anchors, e.g. <a name="x"> or <h2 id="x">
+var htmlAnchorRegex = regexp.MustCompile(`<[a-zA-Z][^>]*?\s(?:name|id)=["']([^"']+)["']`)
+
+// To catch HTML links to anchors
+var htmlHrefRegex = regexp.MustCompile(`\shref=["']([^"']+)["']`)
+
+// Characters dropped from header IDs by kramdown's GFM parser
+var nonWordRegex = regexp.MustCompile(`[^\p{L}\p{M}\p{N}\p{Pc}\- \t]`)
+
+// Markup dropped from the text of a heading
+var (
+	inlineLinkRegex = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	htmlTagRegex    = regexp.MustCompile(`<[^>]+>`)
+)
+
+// To catch the rules of a Netlify _redirects file
+var redirectRegex = regexp.MustCompile(`^\s*(/\S*)\s+\S+`)
+
+// headingID returns the ID that kramdown generates for a heading with text,
+// following the GFM rules the site is built with.
+func headingID(text string) string {
+	text = inlineLinkRegex.ReplaceAllString(text, "$1")
+	text = htmlTagRegex.ReplaceAllString(text, "")
+	text = strings.NewReplacer("`", "", "*", "").Replace(text)
+	id := strings.ToLower(strings.TrimSpace(text))
+	id = nonWordRegex.ReplaceAllString(id, "")
+	return strings.NewReplacer(" ", "-", "\t", "-").Replace(id)
+}
+
+// heading is a heading of a Markdown page.
+type heading struct {
+	Level int
+	Title string
+	// ID is the anchor the site generates for the heading.
+	ID string
+}
+
+// walkMarkdown calls fn for every line of content outside of code blocks and
+// the front matter. For headings, h is set; for setext headings, fn is called
+// with the underline.
+func walkMarkdown(content []byte, fn func(line string, h *heading)) {
+	counts := map[string]int{}
+	newHeading := func(level int, text string) *heading {
+		if m := explicitIDRegex.FindStringSubmatch(text); m != nil {
+			return &heading{Level: level, Title: strings.TrimSpace(text[:len(text)-len(m[0])]), ID: m[1]}
+		}
+		id := headingID(text)
+		h := &heading{Level: level, Title: text, ID: id}
+		if n := counts[id]; n > 0 {
+			h.ID = fmt.Sprintf("%s-%d", id, n)
+		}
+		counts[id]++
+		return h
+	}
+
+	content = marker.SkipFrontMatter(content)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 1024*1024)
+	fence := ""
+	previous := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		inCode := fence != ""
+		fence = updateFence(fence, line)
+		if inCode || fence != "" {
+			previous = ""
+			continue
+		}
+
+		if m := atxHeadingRegex.FindStringSubmatch(line); m != nil {
+			fn(line, newHeading(len(line)-len(strings.TrimLeft(line, "#")), m[1]))
+		} else if m := setextRegex.FindStringSubmatch(line); m != nil && strings.TrimSpace(previous) != "" {
+			level := 1
+			if m[1][0] == '-' {
+				level = 2
+			}
+			fn(line, newHeading(level, strings.TrimSpace(previous)))
+		} else {
+			fn(line, nil)
+		}
+		previous = line
+	}
+}
+
+// updateFence returns the fence of the code block that line leaves the
+// Markdown in, given the fence of the code block it is in, or "" for none.
+func updateFence(fence string, line string) string {
+	trimmed := strings.TrimSpace(line)
+	if fence != "" {
+		if strings.HasPrefix(trimmed, fence) {
+			return ""
+		}
+		return fence
+	}
+	if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+		return trimmed[:3]
+	}
+	return ""
+}
+
+// pageAnchors returns the anchors a page defines, and the links it contains,
+// both outside of code blocks.
+func pageAnchors(content []byte) (anchors map[string]bool, links []string) {
+	anchors = map[string]bool{}
+	walkMarkdown(content, func(line string, h *heading) {
+		if h != nil {
+			anchors[h.ID] = true
+		}
+		for _, m := range htmlAnchorRegex.FindAllStringSubmatch(line, -1) {
+			anchors[m[1]] = true
+		}
+		for _, m := range linkRegex.FindAllStringSubmatch(line, -1) {
+			links = append(links, m[2])
+		}
+		for _, m := range htmlHrefRegex.FindAllStringSubmatch(line, -1) {
+			links = append(links, m[1])
+		}
+	})
+	return anchors, links
+}
+
+// redirectRules returns the source paths of the site's _redirects file as
+// regexes. A missing file has no rules.
+func (im *Importer) redirectRules(siteRoot string) []*regexp.Regexp {
+	data, err := im.FS.ReadFile(filepath.Join(siteRoot, "_redirects"))
+	if err != nil {
+		return nil
+	}
+	var rules []*regexp.Regexp
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		m := redirectRegex.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		from := strings.TrimSuffix(m[1], "/")
+		pattern := strings.Replace(regexp.QuoteMeta(from), `\*`, `.*`, -1)
+		rules = append(rules, regexp.MustCompile("^"+pattern+"/?$"))
+	}
+	return rules
+}
+
+// resolves reports whether the site path p is a file of the site, a page
+// built from one, or redirected.
+func (im *Importer) resolves(siteRoot string, p string, redirects []*regexp.Regexp) bool {
+	for _, rule := range redirects {
+		if rule.MatchString(p) {
+			return true
+		}
+	}
+	rel := strings.TrimSuffix(strings.TrimPrefix(path.Clean(p), "/"), "/")
+	candidates := []string{rel, rel + ".md", rel + ".html", rel + "/index.md", rel + "/index.html"}
+	if rel == "" || rel == "." {
+		candidates = []string{"index.html", "index.md"}
+	}
+	for _, c := range candidates {
+		if info, err := im.FS.Stat(filepath.Join(siteRoot, filepath.FromSlash(c))); err == nil && !info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyLinks checks the in-page anchors and site links of the imported
+// files. It returns the problems of each file that has any.
+func (im *Importer) verifyLinks(siteRoot string, files []string) map[string][]string {
+	redirects := im.redirectRules(siteRoot)
+	broken := map[string][]string{}
+	for _, file := range files {
+		if ext := path.Ext(file); ext != ".md" && ext != ".html" {
+			continue
+		}
+		content, err := im.FS.ReadFile(filepath.Join(siteRoot, filepath.FromSlash(file)))
+		if err != nil {
+			broken[file] = append(broken[file], err.Error())
+			continue
+		}
+		anchors, links := pageAnchors(content)
+		for _, link := range links {
+			switch {
+			case strings.HasPrefix(link, "#"):
+				if link != "#" && !anchors[link[1:]] {
+					broken[file] = append(broken[file], fmt.Sprintf("anchor %q does not match a heading", link))
+				}
+			case strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//"):
+				p := link
+				if i := strings.IndexAny(p, "?#"); i >= 0 {
+					p = p[:i]
+				}
+				if !im.resolves(siteRoot, p, redirects) {
+					broken[file] = append(broken[file], fmt.Sprintf("link %q does not resolve to a page or a redirect", link))
+				}
+			}
+		}
+	}
+	return broken
+}
@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHeadingID(t *testing.T) {
+	cases := map[string]string{
+		"Welcome":                        "welcome",
+		"Before You Begin":               "before-you-begin",
+		"What's `kubectl` for?":          "whats-kubectl-for",
+		"Use [Minikube](/docs/minikube)": "use-minikube",
+		"v1.10.0 / Action Required":      "v1100--action-required",
+		"snake_case names":               "snake_case-names",
+	}
+	for text, want := range cases {
+		if got := headingID(text); got != want {
+			t.Errorf("headingID(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestVerifyLinks(t *testing.T) {
+	site, err := ioutil.TempDir("", "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(site)
+	files := map[string]string{
+		"_redirects":                "# comment\n/docs/old/     /docs/new/ 301\n/docs/api/*    /docs/reference/ 301\n",
+		"docs/home/index.md":        "---\ntitle: Home\n---\n",
+		"docs/concepts/pods.md":     "---\ntitle: Pods\n---\n",
+		"images/imported/a/123.png": "png",
+		"docs/imported/page.md": `---
+title: Page
+---
+## Overview
+
+Setext Heading
+--------------
+
+## Overview
+
+## Custom {#custom-id}
+
+<a name="legacy"></a>
+
+` + "```" + `
+## Not a heading
+[not a link](#not-a-heading)
+` + "```" + `
+
+[ok](#overview) [ok](#overview-1) [ok](#setext-heading) [ok](#custom-id) [ok](#legacy)
+[broken](#missing) [broken](#not-a-heading)
+[ok](/docs/home/) [ok](/docs/concepts/pods/#containers) [ok](/docs/old/) [ok](/docs/api/v1/pods)
+![ok](/images/imported/a/123.png) [external](https://kubernetes.io/docs/missing/)
+[broken](/docs/missing/) <a href="#nowhere">broken</a>
+`,
+	}
+	for name, content := range files {
+		p := filepath.Join(site, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	im := New(nil)
+	got := im.verifyLinks(site, []string{"docs/imported/page.md", "images/imported/a/123.png"})
+	want := map[string][]string{
+		"docs/imported/page.md": {
+			`anchor "#missing" does not match a heading`,
+			`anchor "#not-a-heading" does not match a heading`,
+			`link "/docs/missing/" does not resolve to a page or a redirect`,
+			`anchor "#nowhere" does not match a heading`,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("verifyLinks() = %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchOptions configures ImportLocal and Watch.
+type WatchOptions struct {
+	// Repo is the name of the repo in the config.
+	Repo string
+	// Dir is a local checkout of the repo, used instead of a clone.
+	Dir string
+	// Version is the release to import into, for a config with releases.
+	// It defaults to the first release.
+	Version string
+}
+
+// watchDelay is how long Watch waits for more changes before it imports,
+// since editors often write a file in several steps.
+const watchDelay = 200 * time.Millisecond
+
+// ImportLocal copies the files of a repo from the local checkout opts.Dir into
+// cfg.SiteRoot, like Run does from a clone. The generate-command and
+// generator of the repo are not run. Broken links are logged, not returned.
+func (im *Importer) ImportLocal(ctx context.Context, cfg Config, opts WatchOptions) (RepoReport, error) {
+	repo, rr, versionDir, err := localRepo(cfg, opts)
+	if err != nil {
+		return rr, err
+	}
+	prefix, err := remotePrefix(repo.Remote, rr.Branch)
+	if err != nil {
+		return rr, &ConfigError{Err: err}
+	}
+
+	if err := im.copyFiles(ctx, cfg, opts.Dir, repo, prefix, versionDir, &rr); err != nil {
+		return rr, err
+	}
+	if broken := im.verifyLinks(cfg.SiteRoot, rr.Files); len(broken) > 0 {
+		im.logf("%v\n", &LinkError{Files: broken})
+	}
+	return rr, nil
+}
+
+// localRepo returns the repo of opts, the report to fill in while importing
+// it and the version directory to import it into.
+func localRepo(cfg Config, opts WatchOptions) (Repo, RepoReport, string, error) {
+	if err := cfg.Validate(); err != nil {
+		return Repo{}, RepoReport{}, "", err
+	}
+	if cfg.SiteRoot == "" || opts.Dir == "" {
+		return Repo{}, RepoReport{}, "", &ConfigError{Err: fmt.Errorf("the site root and local checkout must be set")}
+	}
+	var repo *Repo
+	for i := range cfg.Repos {
+		if cfg.Repos[i].Name == opts.Repo {
+			repo = &cfg.Repos[i]
+		}
+	}
+	if repo == nil {
+		return Repo{}, RepoReport{}, "", &ConfigError{Err: fmt.Errorf("no repo named %q", opts.Repo)}
+	}
+	if len(cfg.Releases) == 0 {
+		if opts.Version != "" {
+			return Repo{}, RepoReport{}, "", &ConfigError{Err: fmt.Errorf("no releases to import version %q into", opts.Version)}
+		}
+		return *repo, RepoReport{Name: repo.Name, Branch: repo.Branch}, "", nil
+	}
+	for _, rel := range cfg.Releases {
+		if opts.Version != "" && rel.Version != opts.Version {
+			continue
+		}
+		branch := repo.Branch
+		if override, ok := rel.Branches[repo.Name]; ok {
+			branch = override
+		}
+		rr := RepoReport{Name: repo.Name, Branch: branch, Version: rel.Version}
+		return *repo, rr, path.Join(path.Clean(cfg.VersionedRoot), rel.Version), nil
+	}
+	return Repo{}, RepoReport{}, "", &ConfigError{Err: fmt.Errorf("no release %q", opts.Version)}
+}
+
+// Watch imports the repo of opts from its local checkout with ImportLocal,
+// then imports it again whenever a file in the directories of its `src`
+// files changes, until ctx is done. A failed import is logged, and the next
+// change is imported again.
+func (im *Importer) Watch(ctx context.Context, cfg Config, opts WatchOptions) error {
+	repo, _, _, err := localRepo(cfg, opts)
+	if err != nil {
+		return err
+	}
+	if repo.GenerateCommand != "" || repo.Generator != "" {
+		im.logf("Not running the generate-command or generator of repo %q, only copying its files\n", repo.Name)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	dirs := watchDirs(opts.Dir, repo)
+	for dir, recursive := range dirs {
+		if recursive {
+			err = watchTree(watcher, dir)
+		} else {
+			err = watcher.Add(dir)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	im.importLocal(ctx, cfg, opts)
+	im.logf("Watching %q for changes, press Ctrl-C to stop...\n", opts.Dir)
+
+	// a stopped timer, started by the first change
+	timer := time.NewTimer(watchDelay)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			return err
+		case event := <-watcher.Events:
+			if strings.HasPrefix(filepath.Base(event.Name), ".") {
+				continue // editor swap files and the like
+			}
+			if event.Op&fsnotify.Create != 0 && inTree(dirs, event.Name) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := watchTree(watcher, event.Name); err != nil {
+						return err
+					}
+				}
+			}
+			timer.Reset(watchDelay)
+		case <-timer.C:
+			im.logf("\nChange detected, importing repo %q again...\n", repo.Name)
+			im.importLocal(ctx, cfg, opts)
+		}
+	}
+}
+
+// importLocal runs ImportLocal and logs the result.
+func (im *Importer) importLocal(ctx context.Context, cfg Config, opts WatchOptions) {
+	rr, err := im.ImportLocal(ctx, cfg, opts)
+	if err != nil {
+		im.logf("%v\n", err)
+		return
+	}
+	im.logf("Imported %d files\n", len(rr.Files)+len(rr.Assets))
+}
+
+// watchDirs returns the directories of the `src` files of repo in the
+// checkout at dir. It maps a directory to true if its subdirectories are
+// watched too, for a `src` that is a directory itself.
+func watchDirs(dir string, repo Repo) map[string]bool {
+	dirs := map[string]bool{}
+	for _, f := range repo.Files {
+		p := filepath.Join(dir, filepath.FromSlash(f.Src))
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			dirs[p] = true
+		} else if !dirs[filepath.Dir(p)] {
+			dirs[filepath.Dir(p)] = false
+		}
+	}
+	return dirs
+}
+
+// inTree reports whether p is one of the directories of dirs that are watched
+// with their subdirectories, or below one.
+func inTree(dirs map[string]bool, p string) bool {
+	for dir, recursive := range dirs {
+		if recursive && (p == dir || strings.HasPrefix(p, dir+string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchTree adds dir and the directories below it to watcher, since
+// fsnotify does not watch subdirectories.
+func watchTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if p != dir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return watcher.Add(p)
+	})
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchImportsChanges(t *testing.T) {
+	root, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	checkout := filepath.Join(root, "community")
+	src := filepath.Join(checkout, "contributors", "guide", "README.md")
+	os.MkdirAll(filepath.Dir(src), 0755)
+	ioutil.WriteFile(src, []byte("# Guide\nFirst version.\n"), 0644)
+
+	cfg, err := ParseConfig([]byte(`
+repos:
+- name: community
+  remote: https://github.com/kubernetes/community.git
+  branch: master
+  generate-command: hack/gen.sh
+  files:
+  - src: contributors/guide/README.md
+    dst: docs/imported/guide.md
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SiteRoot = filepath.Join(root, "site")
+
+	runner := &fakeRunner{}
+	im := &Importer{Git: &fakeGit{}, Exec: runner, FS: OSFS{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- im.Watch(ctx, cfg, WatchOptions{Repo: "community", Dir: checkout})
+	}()
+
+	dst := filepath.Join(cfg.SiteRoot, "docs", "imported", "guide.md")
+	waitFor := func(want string) {
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			if content, err := ioutil.ReadFile(dst); err == nil && strings.Contains(string(content), want) {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		content, _ := ioutil.ReadFile(dst)
+		t.Fatalf("timed out waiting for %q in %s:\n%s", want, dst, content)
+	}
+	waitFor("First version.")
+
+	// editors often save by renaming a new file over the old one
+	tmp := filepath.Join(checkout, "contributors", "guide", ".README.md.swp")
+	ioutil.WriteFile(tmp, []byte("# Guide\nSecond version.\n"), 0644)
+	if err := os.Rename(tmp, src); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("Second version.")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Watch: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Watch did not return after the context was canceled")
+	}
+	if len(runner.ran) != 0 {
+		t.Errorf("expected the generate-command not to run, ran %v", runner.ran)
+	}
+}
+
+func TestImportLocalRelease(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+versioned-root: docs/reference/generated
+releases:
+- version: v1.10
+- version: v1.9
+  branches:
+    kubernetes: release-1.9
+repos:
+- name: kubernetes
+  remote: https://github.com/kubernetes/kubernetes.git
+  branch: master
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SiteRoot = "/site"
+
+	cases := []struct {
+		opts                        WatchOptions
+		branch, version, versionDir string
+	}{
+		{WatchOptions{Repo: "kubernetes", Dir: "/k"}, "master", "v1.10", "docs/reference/generated/v1.10"},
+		{WatchOptions{Repo: "kubernetes", Dir: "/k", Version: "v1.9"}, "release-1.9", "v1.9", "docs/reference/generated/v1.9"},
+	}
+	for _, c := range cases {
+		_, rr, versionDir, err := localRepo(cfg, c.opts)
+		if err != nil {
+			t.Fatalf("%+v: %v", c.opts, err)
+		}
+		if rr.Branch != c.branch || rr.Version != c.version || versionDir != c.versionDir {
+			t.Errorf("%+v: unexpected branch %q, version %q and directory %q", c.opts, rr.Branch, rr.Version, versionDir)
+		}
+	}
+	for _, opts := range []WatchOptions{
+		{Repo: "community", Dir: "/k"},
+		{Repo: "kubernetes", Dir: "/k", Version: "v1.8"},
+	} {
+		if _, _, _, err := localRepo(cfg, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		} else if _, ok := err.(*ConfigError); !ok {
+			t.Errorf("%+v: expected a *ConfigError, got %v", opts, err)
+		}
+	}
+}
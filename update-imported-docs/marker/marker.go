@@ -0,0 +1,68 @@
+// Package marker stamps the files imported by update-imported-docs with a
+// "DO NOT EDIT" marker, and checks that they were not edited since. It only
+// uses the standard library, so that the tests of the site can use it.
+package marker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// Note is what the marker tells people who open an imported file.
+const Note = "DO NOT EDIT. This file is imported by update-imported-docs, and the next import overwrites changes to it. Edit it upstream instead."
+
+// Regex catches the marker and the hash it records.
+var Regex = regexp.MustCompile(`(?m)^(?:<!-- |# )DO NOT EDIT\. .* sha256:([0-9a-f]{64})(?: -->)?\n`)
+
+// Stamp adds a marker with the hash of the body of data to the site file p,
+// after the front matter. The front matter stays editable, imports keep it.
+// Only pages and YAML data files are stamped.
+func Stamp(p string, data []byte) []byte {
+	var format string
+	switch path.Ext(p) {
+	case ".md", ".html":
+		format = "<!-- %s sha256:%s -->\n"
+	case ".yml", ".yaml":
+		format = "# %s sha256:%s\n"
+	default:
+		return data
+	}
+	body := SkipFrontMatter(data)
+	sum := sha256.Sum256(body)
+	var out bytes.Buffer
+	out.Write(data[:len(data)-len(body)])
+	fmt.Fprintf(&out, format, Note, hex.EncodeToString(sum[:]))
+	out.Write(body)
+	return out.Bytes()
+}
+
+// Check reports whether content has the marker of an import and, if so,
+// whether what follows the marker is unchanged since.
+func Check(content []byte) (stamped bool, unchanged bool) {
+	loc := Regex.FindSubmatchIndex(content)
+	if loc == nil {
+		return false, false
+	}
+	// the marker is the first line after the front matter
+	if len(SkipFrontMatter(content)) != len(content)-loc[0] {
+		return false, false
+	}
+	sum := sha256.Sum256(content[loc[1]:])
+	return true, hex.EncodeToString(sum[:]) == string(content[loc[2]:loc[3]])
+}
+
+// skipFrontMatter returns content without its leading front matter block.
+func SkipFrontMatter(content []byte) []byte {
+	if !bytes.HasPrefix(content, []byte("---\n")) {
+		return content
+	}
+	end := bytes.Index(content[4:], []byte("\n---\n"))
+	if end < 0 {
+		return content
+	}
+	return content[4+end+5:]
+}
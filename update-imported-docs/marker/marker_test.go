@@ -0,0 +1,38 @@
+package marker
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	page := Stamp("docs/a.md", []byte("---\ntitle: A\n---\n# A\nbody\n"))
+	if stamped, unchanged := Check(page); !stamped || !unchanged {
+		t.Errorf("fresh page: stamped %v, unchanged %v", stamped, unchanged)
+	}
+	edited := []byte(string(page) + "an edit\n")
+	if stamped, unchanged := Check(edited); !stamped || unchanged {
+		t.Errorf("edited page: stamped %v, unchanged %v", stamped, unchanged)
+	}
+	// the front matter is kept by imports, so it may be edited
+	retitled := []byte("---\ntitle: B\n---\n" + string(page[len("---\ntitle: A\n---\n"):]))
+	if stamped, unchanged := Check(retitled); !stamped || !unchanged {
+		t.Errorf("retitled page: stamped %v, unchanged %v", stamped, unchanged)
+	}
+	data := Stamp("_data/keps.yml", []byte("- title: A\n"))
+	if stamped, unchanged := Check(data); !stamped || !unchanged {
+		t.Errorf("data file: stamped %v, unchanged %v", stamped, unchanged)
+	}
+	if stamped, _ := Check([]byte("---\ntitle: A\n---\nbody\n")); stamped {
+		t.Errorf("page without marker is reported as stamped")
+	}
+}
+
+func TestSkipFrontMatter(t *testing.T) {
+	for content, want := range map[string]string{
+		"---\ntitle: A\n---\nbody\n": "body\n",
+		"body\n---\n":                "body\n---\n",
+		"---\ntitle: A\nbody\n":      "---\ntitle: A\nbody\n",
+	} {
+		if got := string(SkipFrontMatter([]byte(content))); got != want {
+			t.Errorf("SkipFrontMatter(%q) = %q, want %q", content, got, want)
+		}
+	}
+}